@@ -287,14 +287,25 @@ func getDeluxeUnits() Units {
 			IsShip:     true,
 			CanBombard: true,
 		},
+		// Transports are defenseless, as in 1940
 		Unit{
-			Alias:       "acc",
-			Name:        "AIRCRAFT CARRIER",
-			Cost:        16,
+			Alias:       "tra",
+			Name:        "TRANSPORT",
+			Cost:        7,
 			Attack:      0,
-			Defend:      1,
+			Defend:      0,
 			IsShip:      true,
-			CapitalShip: true,
+			IsTransport: true,
+		},
+		Unit{
+			Alias:           "acc",
+			Name:            "AIRCRAFT CARRIER",
+			Cost:            16,
+			Attack:          0,
+			Defend:          1,
+			IsShip:          true,
+			CapitalShip:     true,
+			CarrierCapacity: 2,
 		},
 		Unit{
 			Alias:       "bts",
@@ -509,14 +520,25 @@ func get1940DeluxeUnits() Units {
 			Defend: 5,
 			IsShip: true,
 		},
+		// Transports are defenseless, as in 1940
 		Unit{
-			Alias:       "acc",
-			Name:        "AIRCRAFT CARRIER",
-			Cost:        16,
-			Attack:      1,
-			Defend:      2,
+			Alias:       "tra",
+			Name:        "TRANSPORT",
+			Cost:        7,
+			Attack:      0,
+			Defend:      0,
 			IsShip:      true,
-			CapitalShip: true,
+			IsTransport: true,
+		},
+		Unit{
+			Alias:           "acc",
+			Name:            "AIRCRAFT CARRIER",
+			Cost:            16,
+			Attack:          1,
+			Defend:          2,
+			IsShip:          true,
+			CapitalShip:     true,
+			CarrierCapacity: 2,
 		},
 		Unit{
 			Alias:       "bts",
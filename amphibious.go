@@ -0,0 +1,275 @@
+package oddsengine
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// AmphibiousAssault describes the seaborne part of an attack on a territory.
+// The attackers passed in along with it are the units joining the assault
+// from land and air.
+type AmphibiousAssault struct {
+	// Seaborne are the land units offloaded from transports. They fight along
+	// with the attackers, but are unable to retreat
+	Seaborne map[string]int `json:"seaborne"`
+
+	// Fleet are the attacking ships and aircraft in the sea zone. The ships
+	// able to bombard fire one shot per seaborne unit at most, the strongest
+	// ships first
+	Fleet map[string]int `json:"fleet"`
+
+	// SeaDefenders are the defending units in the sea zone. When there are
+	// any, the fleet fights them first and only its surviving ships bombard.
+	// The seaborne units land only once the sea zone has been cleared
+	SeaDefenders map[string]int `json:"seaDefenders"`
+}
+
+// amphibiousAssault is an AmphibiousAssault ready to be resolved
+type amphibiousAssault struct {
+	seaborne     map[string]int
+	fleet        map[string]int
+	seaDefenders map[string]int
+	seaOol       *conflictOol
+}
+
+// landing is the seaborne part of an amphibious assault, as it reaches the
+// territory
+type landing struct {
+	// retreatable are the attacking units, by alias, able to retreat
+	retreatable map[string]int
+
+	// bombard are the ships bombarding the territory
+	bombard map[string]int
+}
+
+// GetAmphibiousSummary returns a summary of an amphibious assault. Runs
+// against the default Simulator.
+func GetAmphibiousSummary(attackers, defenders map[string]int, assault AmphibiousAssault) (*Summary, error) {
+	return defaultSimulator.GetAmphibiousSummary(attackers, defenders, assault)
+}
+
+// GetAmphibiousSummaryContext returns a summary of an amphibious assault,
+// stopping early when ctx is done. Runs against the default Simulator.
+func GetAmphibiousSummaryContext(ctx context.Context, attackers, defenders map[string]int, assault AmphibiousAssault) (*Summary, error) {
+	return defaultSimulator.GetAmphibiousSummaryContext(ctx, attackers, defenders, assault)
+}
+
+// GetAmphibiousSummary returns a summary of an amphibious assault on the
+// defenders, by the attackers along with the seaborne units and fleet of the
+// assault.
+func (s *Simulator) GetAmphibiousSummary(attackers, defenders map[string]int, assault AmphibiousAssault) (*Summary, error) {
+	return s.GetAmphibiousSummaryContext(context.Background(), attackers, defenders, assault)
+}
+
+// GetAmphibiousSummaryContext returns a summary of an amphibious assault,
+// stopping early when ctx is cancelled or its deadline passes, just like
+// GetSummaryContext. Any sea battle is fought first, its IPC losses being
+// counted along with those of the land battle.
+func (s *Simulator) GetAmphibiousSummaryContext(ctx context.Context, attackers, defenders map[string]int, assault AmphibiousAssault) (*Summary, error) {
+	err := s.checkAmphibiousAssaultUnits(attackers, defenders, assault)
+	if err != nil {
+		return &Summary{}, err
+	}
+
+	err = s.checkIterations()
+	if err != nil {
+		return &Summary{}, err
+	}
+
+	a := copyFormation(attackers)
+	seaborne := copyFormation(assault.Seaborne)
+
+	land := copyFormation(a)
+	for alias, n := range seaborne {
+		land[alias] += n
+	}
+
+	w := *s
+	w.assault = &amphibiousAssault{
+		seaborne:     seaborne,
+		fleet:        assault.Fleet,
+		seaDefenders: assault.SeaDefenders,
+		seaOol:       s.customizeOol(assault.Fleet, assault.SeaDefenders),
+	}
+
+	acc, err := w.simulate(ctx, a, defenders, s.customizeOol(land, defenders))
+	summary := acc.summary()
+
+	if err != nil {
+		summary.Incomplete = true
+		return summary, err
+	}
+
+	return summary, nil
+}
+
+// resolveAmphibiousAssault resolves the sea battle of the assault, if there is
+// one, followed by the conflict for the territory.
+func (s *Simulator) resolveAmphibiousAssault(a, d map[string]int, ool *conflictOol) *ConflictProfile {
+	fleet := s.assault.fleet
+	sea := new(ConflictProfile)
+
+	// Whatever is left of the fleet stays in the sea zone, surviving the
+	// assault
+	atSea := copyFormation(fleet)
+
+	// The sea battle is fought to the end, the retreat policy and maximum
+	// number of rounds only apply to the territory
+	if len(s.assault.seaDefenders) > 0 {
+		w := *s
+		w.assault = nil
+		w.retreat = RetreatPolicy{}
+		w.maxRounds = 0
+		sea = w.fight(fleet, s.assault.seaDefenders, s.assault.seaOol, nil)
+
+		atSea = map[string]int{}
+		for _, units := range [][]map[string]int{sea.AttackerUnitsRemaining, sea.AttackerUnitsSubmerged} {
+			for _, unit := range units {
+				for alias, n := range unit {
+					atSea[alias] += n
+				}
+			}
+		}
+
+		fleet = map[string]int{}
+		if sea.Outcome == AttackerWin {
+			for _, unit := range sea.AttackerUnitsRemaining {
+				for alias, n := range unit {
+					fleet[alias] = n
+				}
+			}
+		}
+	}
+
+	attackers := copyFormation(a)
+	l := &landing{retreatable: a, bombard: map[string]int{}}
+
+	// The seaborne units never land in a sea zone still held by the enemy
+	if sea.Outcome == AttackerWin || len(s.assault.seaDefenders) == 0 {
+		for alias, n := range s.assault.seaborne {
+			attackers[alias] += n
+		}
+		l.bombard = s.bombardingShips(fleet, getTotalNumUnits(s.assault.seaborne))
+	}
+
+	profile := s.fight(attackers, d, ool, l)
+	profile.AttackerIpcLoss += sea.AttackerIpcLoss
+	profile.DefenderIpcLoss += sea.DefenderIpcLoss
+
+	// The ships which bombarded are recorded as such, the rest of the fleet
+	// as left at sea
+	for _, unit := range profile.AttackerUnitsBombarding {
+		for alias, n := range unit {
+			removeUnits(atSea, alias, n)
+		}
+	}
+	if len(atSea) > 0 {
+		profile.AttackerUnitsAtSea = formationToSortedSlice(atSea)
+	}
+
+	return profile
+}
+
+// removeUnits removes n units of the alias from the formation, whatever their
+// modifiers
+func removeUnits(formation map[string]int, alias string, n int) {
+	for _, key := range []string{alias, "+" + alias, "-" + alias, "*" + alias} {
+		taken := formation[key]
+		if taken > n {
+			taken = n
+		}
+		if taken == 0 {
+			continue
+		}
+
+		n -= taken
+		formation[key] -= taken
+		if formation[key] == 0 {
+			delete(formation, key)
+		}
+	}
+}
+
+// bombardingShips picks the ships of the fleet which bombard the territory,
+// no more than max of them. The ships with the highest attack are picked
+// first.
+func (s *Simulator) bombardingShips(fleet map[string]int, max int) map[string]int {
+	ships := make([]string, len(s.bombardShips))
+	copy(ships, s.bombardShips)
+	sort.SliceStable(ships, func(i, j int) bool {
+		return s.units.Find(ships[i]).Attack > s.units.Find(ships[j]).Attack
+	})
+
+	bombard := map[string]int{}
+	for _, alias := range ships {
+		n := numAllUnitsInFormation(fleet, alias)
+		if n > max {
+			n = max
+		}
+		if n > 0 {
+			bombard[alias] = n
+			max -= n
+		}
+	}
+
+	return bombard
+}
+
+// withdraw moves the attackers able to retreat into withdrawn. The seaborne
+// units share their formation key with the other units of their kind, so
+// every unit able to retreat is withdrawn while enough of its kind remain,
+// only the units left over staying behind.
+func withdraw(attackers, retreatable, withdrawn map[string]int) {
+	for alias, n := range retreatable {
+		if attackers[alias] < n {
+			n = attackers[alias]
+		}
+		if n == 0 {
+			continue
+		}
+
+		withdrawn[alias] += n
+		attackers[alias] -= n
+		if attackers[alias] == 0 {
+			delete(attackers, alias)
+		}
+	}
+}
+
+// checkAmphibiousAssaultUnits makes sure every unit of the assault is valid,
+// the seaborne units being land units and the units in the sea zone being
+// ships or aircraft. Ships support the assault from the fleet only.
+func (s *Simulator) checkAmphibiousAssaultUnits(attackers, defenders map[string]int, assault AmphibiousAssault) error {
+	formations := []map[string]int{attackers, defenders, assault.Seaborne, assault.Fleet, assault.SeaDefenders}
+	for _, formation := range formations {
+		if err := s.checkUnitValidity(formation); err != nil {
+			return err
+		}
+	}
+
+	for _, formation := range []map[string]int{attackers, assault.Seaborne} {
+		for alias := range formation {
+			if s.units.Find(realAlias(alias)).IsShip {
+				return &InvalidUnitError{fmt.Sprintf("Unit %s must support the assault from the fleet", alias)}
+			}
+		}
+	}
+
+	for alias := range assault.Seaborne {
+		if s.units.Find(realAlias(alias)).IsAircraft {
+			return &InvalidUnitError{fmt.Sprintf("Unit %s can not be carried by sea", alias)}
+		}
+	}
+
+	for _, formation := range []map[string]int{assault.Fleet, assault.SeaDefenders} {
+		for alias := range formation {
+			unit := s.units.Find(realAlias(alias))
+			if !unit.IsShip && !unit.IsAircraft {
+				return &InvalidUnitError{fmt.Sprintf("Unit %s can not fight at sea", alias)}
+			}
+		}
+	}
+
+	return nil
+}
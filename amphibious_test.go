@@ -0,0 +1,142 @@
+package oddsengine
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBombardingShips(t *testing.T) {
+	values := []struct {
+		fleet   map[string]int
+		max     int
+		bombard map[string]int
+	}{
+		{map[string]int{"bat": 2, "cru": 2, "des": 1}, 3, map[string]int{"bat": 2, "cru": 1}},
+		{map[string]int{"-bat": 1, "cru": 1}, 5, map[string]int{"bat": 1, "cru": 1}},
+		{map[string]int{"bat": 2}, 0, map[string]int{}},
+		{map[string]int{"des": 2, "car": 1}, 2, map[string]int{}},
+	}
+
+	s := NewSimulator()
+	for _, tt := range values {
+		if actual := s.bombardingShips(tt.fleet, tt.max); !reflect.DeepEqual(tt.bombard, actual) {
+			t.Errorf("bombarding ships were not picked correctly\nexpected: %v\nactual: %v", tt.bombard, actual)
+		}
+	}
+}
+
+func TestWithdraw(t *testing.T) {
+	attackers := map[string]int{"inf": 3, "tan": 1, "fig": 1}
+	withdrawn := map[string]int{}
+
+	// Two of the infantry and the tank landed by sea, the seaborne tank has
+	// already been lost
+	withdraw(attackers, map[string]int{"inf": 1, "tan": 1, "fig": 1}, withdrawn)
+
+	if !reflect.DeepEqual(attackers, map[string]int{"inf": 2}) {
+		t.Errorf("seaborne units did not stay\nactual: %v", attackers)
+	}
+	if !reflect.DeepEqual(withdrawn, map[string]int{"inf": 1, "tan": 1, "fig": 1}) {
+		t.Errorf("units were not withdrawn\nactual: %v", withdrawn)
+	}
+}
+
+func TestAmphibiousAssault(t *testing.T) {
+	// Bombardment is capped at one shot per seaborne unit
+	dice := &countingDice{}
+	s := NewSimulator(WithDice(dice), WithWorkers(1), WithIterations(1))
+	_, err := s.GetAmphibiousSummary(map[string]int{}, map[string]int{"inf": 10}, AmphibiousAssault{
+		Seaborne: map[string]int{"inf": 2},
+		Fleet:    map[string]int{"bat": 3},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dice.rolls != 14 {
+		t.Errorf("bombardment was not capped\nexpected: 14 rolls\nactual: %v", dice.rolls)
+	}
+
+	// Seaborne units fight on after the rest of the attackers retreat
+	s = NewSimulator(WithRetreat(RetreatPolicy{AfterRounds: 1}), WithIterations(1000), WithSeed(1))
+	summary, err := s.GetAmphibiousSummary(map[string]int{"inf": 2, "fig": 1}, map[string]int{"inf": 4}, AmphibiousAssault{
+		Seaborne: map[string]int{"inf": 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.AverageRounds <= 1 || summary.AttackerWinPercentage == 0 || summary.DefenderWinPercentage != 0 {
+		t.Errorf("seaborne units did not fight on\n%+v", summary)
+	}
+
+	// The seaborne units never land when the sea battle is lost
+	s = NewSimulator(WithIterations(100))
+	summary, err = s.GetAmphibiousSummary(map[string]int{}, map[string]int{"inf": 1}, AmphibiousAssault{
+		Seaborne:     map[string]int{"tan": 5},
+		Fleet:        map[string]int{"tra": 2},
+		SeaDefenders: map[string]int{"des": 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.DefenderWinPercentage != 100 || summary.AttackerAvgIpcLoss != 14 {
+		t.Errorf("seaborne units landed in a contested sea zone\n%+v", summary)
+	}
+
+	// The maximum number of rounds only limits the land battle, the sea
+	// battle is fought to the end
+	s = NewSimulator(WithMaxRounds(1), WithIterations(2000), WithSeed(1))
+	summary, err = s.GetAmphibiousSummary(map[string]int{}, map[string]int{"inf": 1}, AmphibiousAssault{
+		Seaborne:     map[string]int{"inf": 2},
+		Fleet:        map[string]int{"bat": 2},
+		SeaDefenders: map[string]int{"des": 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.AttackerWinPercentage < 80 {
+		t.Errorf("the sea battle was limited to a round\n%+v", summary)
+	}
+
+	// The fleet survives the assault, bombarding or not
+	s = NewSimulator(WithIterations(100), WithSeed(1))
+	summary, err = s.GetAmphibiousSummary(map[string]int{}, map[string]int{"inf": 3}, AmphibiousAssault{
+		Seaborne: map[string]int{"inf": 1},
+		Fleet:    map[string]int{"bat": 1, "cru": 1, "tra": 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, alias := range []string{"bat", "cru", "tra"} {
+		if survival := summary.AttackerUnitSurvival[alias]; survival.SurvivalPercentage != 100 || survival.ExpectedSurvivors != 1 {
+			t.Errorf("%v of the fleet did not survive\n%+v", alias, summary.AttackerUnitSurvival)
+		}
+	}
+
+	w := *s
+	w.assault = &amphibiousAssault{seaborne: map[string]int{"inf": 1}, fleet: map[string]int{"bat": 1, "cru": 1, "tra": 1}}
+	attackers, defenders := map[string]int{}, map[string]int{"inf": 3}
+	profile := w.resolveConflict(attackers, defenders, w.customizeOol(map[string]int{"inf": 1}, defenders))
+	bombarding, atSea := []map[string]int{{"bat": 1}}, []map[string]int{{"cru": 1}, {"tra": 1}}
+	if !reflect.DeepEqual(bombarding, profile.AttackerUnitsBombarding) || !reflect.DeepEqual(atSea, profile.AttackerUnitsAtSea) {
+		t.Errorf("the fleet was not recorded correctly\nbombarding: %v\nat sea: %v", profile.AttackerUnitsBombarding, profile.AttackerUnitsAtSea)
+	}
+}
+
+func TestAmphibiousAssaultUnitValidity(t *testing.T) {
+	values := []struct {
+		attackers map[string]int
+		assault   AmphibiousAssault
+	}{
+		{map[string]int{"bat": 1}, AmphibiousAssault{Seaborne: map[string]int{"inf": 1}}},
+		{map[string]int{}, AmphibiousAssault{Seaborne: map[string]int{"fig": 1}}},
+		{map[string]int{}, AmphibiousAssault{Seaborne: map[string]int{"inf": 1}, Fleet: map[string]int{"art": 1}}},
+		{map[string]int{}, AmphibiousAssault{Seaborne: map[string]int{"mec": 1}, SeaDefenders: map[string]int{"inf": 1}}},
+	}
+
+	s := NewSimulator()
+	for _, tt := range values {
+		if _, err := s.GetAmphibiousSummary(tt.attackers, map[string]int{"inf": 1}, tt.assault); err == nil {
+			t.Errorf("invalid assault did not error\nattackers: %v\nassault: %+v", tt.attackers, tt.assault)
+		}
+	}
+}
@@ -0,0 +1,412 @@
+package oddsengine
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// defaultFacilityAAAHitValue is the hit value of the anti-aircraft defences
+// built into every facility, in games without an AAA unit to take it from.
+const defaultFacilityAAAHitValue = 1
+
+// Facility represents a target of a strategic bombing raid.
+type Facility struct {
+	Alias string
+	Name  string
+
+	// MaxDamage is the most damage the facility can take. When 0 the damage
+	// is capped by the IPC value of the territory instead
+	MaxDamage int
+
+	// DamagePerIpc is the multiple of the territory's IPC value the damage is
+	// capped at, when the facility has no MaxDamage
+	DamagePerIpc int
+
+	// Raiders are the aliases of the units able to raid the facility
+	Raiders []string
+}
+
+// getFacilitiesForGame returns the facilities that can be raided in a game.
+// Games without strategic bombing raids have no facilities.
+func getFacilitiesForGame(game string) []Facility {
+	switch game {
+	case "1940":
+		return []Facility{
+			{Alias: "maj", Name: "Major Industrial Complex", MaxDamage: 20, Raiders: []string{"bom", "hbom"}},
+			{Alias: "min", Name: "Minor Industrial Complex", MaxDamage: 6, Raiders: []string{"bom", "hbom"}},
+			{Alias: "air", Name: "Air Base", MaxDamage: 6, Raiders: []string{"bom", "hbom", "tac"}},
+			{Alias: "nav", Name: "Naval Base", MaxDamage: 6, Raiders: []string{"bom", "hbom", "tac"}},
+		}
+	case "1942":
+		return []Facility{
+			{Alias: "ind", Name: "Industrial Complex", DamagePerIpc: 2, Raiders: []string{"bom"}},
+		}
+	case "1941":
+		return []Facility{
+			{Alias: "ind", Name: "Industrial Complex", DamagePerIpc: 1, Raiders: []string{"bom"}},
+		}
+	}
+
+	return nil
+}
+
+// BombingRaidSummary is a type which represents the averaged results of many
+// strategic bombing raids.
+type BombingRaidSummary struct {
+	// TotalSimulations The number of raids that have been ran
+	TotalSimulations int `json:"totalSimulations"`
+
+	// Incomplete is true when the simulation was stopped before every
+	// iteration was ran. The summary only covers TotalSimulations raids
+	Incomplete bool `json:"incomplete"`
+
+	// AverageDamage The damage dealt to the facility on average
+	AverageDamage float64 `json:"averageDamage"`
+
+	// MaxDamagePercentage The percentage of raids dealing the most damage the
+	// facility can take
+	MaxDamagePercentage float64 `json:"maxDamagePercentage"`
+
+	// DamageDistribution The number of raids that dealt each amount of damage
+	DamageDistribution map[int]int `json:"damageDistribution"`
+
+	// AAAHitsAverage The number of bombers shot down by the facility on
+	// average
+	AAAHitsAverage float64 `json:"aaaHitsAverage"`
+
+	// AttackerAvgAircraftLoss The number of bombers and escorts lost on
+	// average
+	AttackerAvgAircraftLoss float64 `json:"attackerAvgAircraftLoss"`
+
+	// DefenderAvgAircraftLoss The number of interceptors lost on average
+	DefenderAvgAircraftLoss float64 `json:"defenderAvgAircraftLoss"`
+
+	// AttackerAvgIpcLoss The IPC value of the bombers and escorts lost on
+	// average
+	AttackerAvgIpcLoss float64 `json:"attackerAvgIpcLoss"`
+
+	// DefenderAvgIpcLoss The IPC value of the interceptors lost on average,
+	// the damage to the facility is not included
+	DefenderAvgIpcLoss float64 `json:"defenderAvgIpcLoss"`
+
+	// AverageDamageConfidence The standard error and 95% confidence interval
+	// of the AverageDamage
+	AverageDamageConfidence Interval `json:"averageDamageConfidence"`
+}
+
+// bombingRaidProfile is the outcome of a single strategic bombing raid
+type bombingRaidProfile struct {
+	damage               int
+	aaaHits              int
+	attackerAircraftLoss int
+	defenderAircraftLoss int
+	attackerIpcLoss      int
+	defenderIpcLoss      int
+}
+
+// GetBombingRaidSummary returns a summary of a strategic bombing raid against
+// a facility. Runs against the default Simulator.
+func GetBombingRaidSummary(bombers, escorts, interceptors map[string]int, facility string, territoryValue int) (*BombingRaidSummary, error) {
+	return defaultSimulator.GetBombingRaidSummary(bombers, escorts, interceptors, facility, territoryValue)
+}
+
+// GetBombingRaidSummaryContext returns a summary of a strategic bombing raid
+// against a facility, stopping early when ctx is done. Runs against the
+// default Simulator.
+func GetBombingRaidSummaryContext(ctx context.Context, bombers, escorts, interceptors map[string]int, facility string, territoryValue int) (*BombingRaidSummary, error) {
+	return defaultSimulator.GetBombingRaidSummaryContext(ctx, bombers, escorts, interceptors, facility, territoryValue)
+}
+
+// GetBombingRaidSummary returns a summary of a strategic bombing raid by the
+// bombers against a facility, raided once per iteration. In 1940 the escorts
+// and interceptors fight a round of air combat first. The facility's AAA then
+// fires at every bomber, and each surviving bomber rolls for damage. The
+// territoryValue caps the damage of facilities which have no max damage of
+// their own, and must then be above 0.
+func (s *Simulator) GetBombingRaidSummary(bombers, escorts, interceptors map[string]int, facility string, territoryValue int) (*BombingRaidSummary, error) {
+	return s.GetBombingRaidSummaryContext(context.Background(), bombers, escorts, interceptors, facility, territoryValue)
+}
+
+// GetBombingRaidSummaryContext returns a summary of a strategic bombing raid,
+// like GetBombingRaidSummary, stopping early when ctx is cancelled or its
+// deadline passes. The summary of a stopped simulation covers only the raids
+// resolved so far, is flagged as Incomplete and is returned along with the
+// error of the context. Raids are run for a fixed number of iterations, a
+// Simulator set up WithPrecision is rejected.
+func (s *Simulator) GetBombingRaidSummaryContext(ctx context.Context, bombers, escorts, interceptors map[string]int, facility string, territoryValue int) (*BombingRaidSummary, error) {
+	f, err := s.findFacility(facility)
+	if err != nil {
+		return &BombingRaidSummary{}, err
+	}
+
+	err = s.checkBombingRaidUnits(bombers, escorts, interceptors, f)
+	if err != nil {
+		return &BombingRaidSummary{}, err
+	}
+
+	if _, ok := s.dice.(FaceDice); s.dice != nil && !ok {
+		return &BombingRaidSummary{}, &UnsupportedError{"Bombing raids need dice able to roll the face of a die"}
+	}
+
+	if s.margin > 0 {
+		return &BombingRaidSummary{}, &UnsupportedError{"Bombing raids can not be run to a precision"}
+	}
+
+	err = s.checkIterations()
+	if err != nil {
+		return &BombingRaidSummary{}, err
+	}
+
+	maxDamage := f.MaxDamage
+	if maxDamage == 0 {
+		if territoryValue <= 0 {
+			return &BombingRaidSummary{}, &UnsupportedError{fmt.Sprintf("Raids on a %s need a territory value above 0, got %d", f.Name, territoryValue)}
+		}
+		maxDamage = f.DamagePerIpc * territoryValue
+	}
+
+	// The raids are resolved from the same streams as conflicts, keeping
+	// seeded raids reproducible.
+	acc := newBombingRaidAccumulator(maxDamage)
+	err = s.runStreams(ctx, s.simulationSeed(), 0, s.iterations, func(w *Simulator, first, last int) func() {
+		streamAcc := newBombingRaidAccumulator(maxDamage)
+		for j := first; j < last; j++ {
+			streamAcc.add(w.resolveBombingRaid(bombers, escorts, interceptors, maxDamage))
+		}
+
+		return func() {
+			acc.merge(streamAcc)
+			if s.progress != nil {
+				s.progress(Progress{CompletedIterations: acc.raids, TotalIterations: s.iterations})
+			}
+		}
+	})
+
+	summary := acc.summary()
+
+	if err != nil {
+		summary.Incomplete = true
+		return summary, err
+	}
+
+	return summary, nil
+}
+
+// bombingRaidAccumulator folds bombing raid profiles into running totals, the
+// raids of a stream being merged into those of the simulation
+type bombingRaidAccumulator struct {
+	maxDamage    int
+	raids        int
+	distribution map[int]int
+
+	totalDamage, totalDamageSquared, totalMaxDamage            float64
+	totalAAAHits, totalAttackerAircraft, totalDefenderAircraft float64
+	totalAttackerIpcLoss, totalDefenderIpcLoss                 float64
+}
+
+// newBombingRaidAccumulator creates an empty accumulator of raids dealing at
+// most maxDamage
+func newBombingRaidAccumulator(maxDamage int) *bombingRaidAccumulator {
+	return &bombingRaidAccumulator{maxDamage: maxDamage, distribution: map[int]int{}}
+}
+
+// add folds a single raid into the totals
+func (a *bombingRaidAccumulator) add(p *bombingRaidProfile) {
+	a.raids++
+	a.distribution[p.damage]++
+	a.totalDamage += float64(p.damage)
+	a.totalDamageSquared += float64(p.damage * p.damage)
+	if p.damage == a.maxDamage {
+		a.totalMaxDamage++
+	}
+	a.totalAAAHits += float64(p.aaaHits)
+	a.totalAttackerAircraft += float64(p.attackerAircraftLoss)
+	a.totalDefenderAircraft += float64(p.defenderAircraftLoss)
+	a.totalAttackerIpcLoss += float64(p.attackerIpcLoss)
+	a.totalDefenderIpcLoss += float64(p.defenderIpcLoss)
+}
+
+// merge folds the totals of another accumulator into this one
+func (a *bombingRaidAccumulator) merge(b *bombingRaidAccumulator) {
+	a.raids += b.raids
+	for damage, n := range b.distribution {
+		a.distribution[damage] += n
+	}
+	a.totalDamage += b.totalDamage
+	a.totalDamageSquared += b.totalDamageSquared
+	a.totalMaxDamage += b.totalMaxDamage
+	a.totalAAAHits += b.totalAAAHits
+	a.totalAttackerAircraft += b.totalAttackerAircraft
+	a.totalDefenderAircraft += b.totalDefenderAircraft
+	a.totalAttackerIpcLoss += b.totalAttackerIpcLoss
+	a.totalDefenderIpcLoss += b.totalDefenderIpcLoss
+}
+
+// summary averages the totals into a BombingRaidSummary
+func (a *bombingRaidAccumulator) summary() *BombingRaidSummary {
+	summary := &BombingRaidSummary{
+		TotalSimulations:   a.raids,
+		DamageDistribution: a.distribution,
+	}
+	if a.raids == 0 {
+		return summary
+	}
+
+	total := float64(a.raids)
+	summary.AverageDamage = round(a.totalDamage/total, 2)
+	summary.MaxDamagePercentage = round((a.totalMaxDamage/total)*100, 2)
+	summary.AAAHitsAverage = round(a.totalAAAHits/total, 2)
+	summary.AttackerAvgAircraftLoss = round(a.totalAttackerAircraft/total, 2)
+	summary.DefenderAvgAircraftLoss = round(a.totalDefenderAircraft/total, 2)
+	summary.AttackerAvgIpcLoss = round(a.totalAttackerIpcLoss/total, 2)
+	summary.DefenderAvgIpcLoss = round(a.totalDefenderIpcLoss/total, 2)
+	summary.AverageDamageConfidence = normalInterval(a.totalDamage, a.totalDamageSquared, total)
+
+	return summary
+}
+
+// resolveBombingRaid resolves a single strategic bombing raid, returning the
+// profile of the raid. The damage is capped at maxDamage.
+func (s *Simulator) resolveBombingRaid(b, e, i map[string]int, maxDamage int) *bombingRaidProfile {
+	bombers := copyFormation(b)
+	escorts := copyFormation(e)
+	interceptors := copyFormation(i)
+
+	profile := new(bombingRaidProfile)
+	numAttackers := getTotalNumUnits(bombers) + getTotalNumUnits(escorts)
+	numInterceptors := getTotalNumUnits(interceptors)
+
+	/**
+	 * Air Combat
+	 *
+	 * A single round between the interceptors and the bombers and escorts,
+	 * the attacking aircraft hit at 1 and the interceptors at 2. Escorts are
+	 * taken as casualties before the bombers.
+	 */
+	if numInterceptors > 0 {
+		attackingHits := s.calculateHits(RollMap{{1, numAttackers}})
+		defendingHits := s.calculateHits(RollMap{{2, numInterceptors}})
+
+		profile.defenderIpcLoss += s.takeCasualties(interceptors, attackingHits, s.baseOol)
+
+		escortHits := defendingHits
+		if n := getTotalNumUnits(escorts); escortHits > n {
+			escortHits = n
+		}
+		profile.attackerIpcLoss += s.takeCasualties(escorts, escortHits, s.baseOol) +
+			s.takeCasualties(bombers, defendingHits-escortHits, s.baseOol)
+	}
+
+	/**
+	 * Facility AAA
+	 *
+	 * Fires once at every surviving bomber, the escorts do not fly over the
+	 * target.
+	 */
+	profile.aaaHits = s.calculateSpecialHits(RollMap{{s.facilityAAAHitValue(), getTotalNumUnits(bombers)}})
+	profile.attackerIpcLoss += s.takeCasualties(bombers, profile.aaaHits, s.baseOol)
+
+	/**
+	 * Bombing
+	 *
+	 * Every surviving bomber rolls for damage, multi roll units keeping the
+	 * highest of their dice. The bombers roll in a fixed order, so that
+	 * seeded raids are reproducible.
+	 */
+	aliases := make([]string, 0, len(bombers))
+	for alias := range bombers {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+
+	for _, alias := range aliases {
+		unit := s.units.Find(realAlias(alias))
+		for j := 0; j < bombers[alias]; j++ {
+			profile.damage += s.rollDamage(unit)
+		}
+	}
+	if profile.damage > maxDamage {
+		profile.damage = maxDamage
+	}
+
+	profile.attackerAircraftLoss = numAttackers - getTotalNumUnits(bombers) - getTotalNumUnits(escorts)
+	profile.defenderAircraftLoss = numInterceptors - getTotalNumUnits(interceptors)
+
+	return profile
+}
+
+// facilityAAAHitValue returns the hit value of the facility AAA, the defence of
+// the game's AAA unit
+func (s *Simulator) facilityAAAHitValue() int {
+	if !s.units.HasUnit("aaa") {
+		return defaultFacilityAAAHitValue
+	}
+
+	return s.units.Find("aaa").Defend
+}
+
+// rollDamage rolls the bombing damage of a single bomber. Strategic and heavy
+// bombers add 2 to their damage in 1940.
+func (s *Simulator) rollDamage(unit *Unit) int {
+	damage := s.rollDie()
+	for d := 1; d < unit.MultiRoll; d++ {
+		if roll := s.rollDie(); roll > damage {
+			damage = roll
+		}
+	}
+
+	if s.game == "1940" && (unit.Alias == "bom" || unit.Alias == "hbom") {
+		damage += 2
+	}
+
+	return damage
+}
+
+// findFacility returns the facility of the simulator's game identified by the
+// alias
+func (s *Simulator) findFacility(alias string) (*Facility, error) {
+	facilities := getFacilitiesForGame(s.game)
+	if facilities == nil {
+		return nil, &UnsupportedError{fmt.Sprintf("Game %s has no strategic bombing raids", s.game)}
+	}
+
+	for i := range facilities {
+		if facilities[i].Alias == alias {
+			return &facilities[i], nil
+		}
+	}
+
+	return nil, &InvalidUnitError{fmt.Sprintf("Facility %s is not valid for game %s", alias, s.game)}
+}
+
+// checkBombingRaidUnits makes sure the bombers are able to raid the facility,
+// and that the escorts and interceptors are aircraft able to fight in the
+// simulator's game.
+func (s *Simulator) checkBombingRaidUnits(bombers, escorts, interceptors map[string]int, f *Facility) error {
+	for _, formation := range []map[string]int{bombers, escorts, interceptors} {
+		if err := s.checkUnitValidity(formation); err != nil {
+			return err
+		}
+	}
+
+	for alias := range bombers {
+		if !sliceHasValue(f.Raiders, realAlias(alias)) {
+			return &InvalidUnitError{fmt.Sprintf("Unit %s is not able to raid a %s", alias, f.Name)}
+		}
+	}
+
+	for _, formation := range []map[string]int{escorts, interceptors} {
+		if len(formation) > 0 && s.game != "1940" {
+			return &UnsupportedError{fmt.Sprintf("Escorts and interceptors do not fight in game %s", s.game)}
+		}
+
+		for alias := range formation {
+			if !s.units.Find(realAlias(alias)).IsAircraft {
+				return &InvalidUnitError{fmt.Sprintf("Unit %s is not an aircraft", alias)}
+			}
+		}
+	}
+
+	return nil
+}
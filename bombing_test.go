@@ -0,0 +1,199 @@
+package oddsengine
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// scriptedFaceDice rolls the faces of the scripted sequence as well
+type scriptedFaceDice struct {
+	*scriptedDice
+}
+
+func (d scriptedFaceDice) Face(sides int) int {
+	face := d.faces[d.next%len(d.faces)]
+	d.next++
+	return face
+}
+
+func TestBombingRaidDamage(t *testing.T) {
+	values := []struct {
+		game           string
+		bombers        map[string]int
+		facility       string
+		territoryValue int
+		minDamage      int
+		maxDamage      int
+		averageDamage  float64
+	}{
+		// 1d6+2 for every bomber surviving the AAA, 1 in 6 is shot down
+		{"1940", map[string]int{"bom": 1}, "maj", 0, 3, 8, 5.5 * 5 / 6},
+		{"1940", map[string]int{"bom": 3}, "min", 0, 0, 6, 5.9},
+		{"1940", map[string]int{"tac": 1}, "air", 0, 1, 6, 3.5 * 5 / 6},
+		{"1942", map[string]int{"bom": 1}, "ind", 10, 1, 6, 3.5 * 5 / 6},
+		// Capped at twice the territory value in 1942
+		{"1942", map[string]int{"bom": 2}, "ind", 1, 0, 2, 1.9},
+		{"1941", map[string]int{"bom": 2}, "ind", 3, 0, 3, 2.76},
+	}
+
+	for _, tt := range values {
+		s := NewSimulator(WithGame(tt.game), WithIterations(20000), WithSeed(1))
+		summary, err := s.GetBombingRaidSummary(tt.bombers, nil, nil, tt.facility, tt.territoryValue)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var raids int
+		for damage, n := range summary.DamageDistribution {
+			raids += n
+			if damage != 0 && (damage < tt.minDamage || damage > tt.maxDamage) {
+				t.Errorf("bombing damage out of range\nbombers: %v\nfacility: %v\ndamage: %v", tt.bombers, tt.facility, damage)
+			}
+		}
+		if raids != 20000 || summary.TotalSimulations != 20000 {
+			t.Errorf("every raid was not recorded\n%+v", summary)
+		}
+
+		if math.Abs(summary.AverageDamage-tt.averageDamage) > 0.1 {
+			t.Errorf("bombing damage average was incorrect\nbombers: %v\nfacility: %v\nexpected: %v\nactual: %v", tt.bombers, tt.facility, tt.averageDamage, summary.AverageDamage)
+		}
+		if conf := summary.AverageDamageConfidence; summary.AverageDamage < conf.Lower || summary.AverageDamage > conf.Upper {
+			t.Errorf("bombing damage confidence did not contain the average\n%+v", summary)
+		}
+	}
+}
+
+func TestBombingRaidAirCombat(t *testing.T) {
+	// The attackers hit the interceptor on the first of their two dice, the
+	// interceptor hits the escort, the AAA misses and the bomber rolls a 4.
+	dice := scriptedFaceDice{&scriptedDice{faces: []int{1, 6, 2, 6, 4}}}
+	s := NewSimulator(WithDice(dice), WithWorkers(1), WithIterations(1))
+
+	summary, err := s.GetBombingRaidSummary(map[string]int{"bom": 1}, map[string]int{"fig": 1}, map[string]int{"fig": 1}, "maj", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &BombingRaidSummary{
+		TotalSimulations:        1,
+		AverageDamage:           6,
+		DamageDistribution:      map[int]int{6: 1},
+		AttackerAvgAircraftLoss: 1,
+		DefenderAvgAircraftLoss: 1,
+		AttackerAvgIpcLoss:      10,
+		DefenderAvgIpcLoss:      10,
+		AverageDamageConfidence: Interval{Lower: 6, Upper: 6},
+	}
+	if !reflect.DeepEqual(summary, expected) {
+		t.Errorf("air combat was not resolved correctly\nexpected: %+v\nactual: %+v", expected, summary)
+	}
+}
+
+func TestBombingRaidSeed(t *testing.T) {
+	// Bombers of several kinds roll in the same order on every raid
+	raid := func() *BombingRaidSummary {
+		s := NewSimulator(WithIterations(2000), WithSeed(7))
+		summary, err := s.GetBombingRaidSummary(map[string]int{"bom": 1, "hbom": 1}, nil, nil, "maj", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return summary
+	}
+
+	first := raid()
+	for i := 0; i < 20; i++ {
+		if summary := raid(); !reflect.DeepEqual(first, summary) {
+			t.Fatalf("seeded raids were not reproducible\nfirst: %+v\nactual: %+v", first, summary)
+		}
+	}
+}
+
+func TestBombingRaidContext(t *testing.T) {
+	bombers := map[string]int{"bom": 2}
+
+	// Raids are resolved on every worker and reported as they go
+	var reports []Progress
+	s := NewSimulator(WithIterations(1050), WithSeed(1), WithProgress(func(p Progress) {
+		reports = append(reports, p)
+	}))
+	summary, err := s.GetBombingRaidSummary(bombers, nil, nil, "maj", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 11 || reports[10].CompletedIterations != 1050 || reports[10].TotalIterations != 1050 {
+		t.Errorf("raid progress was not reported correctly\n%+v", reports)
+	}
+
+	single, err := NewSimulator(WithIterations(1050), WithSeed(1), WithWorkers(1)).GetBombingRaidSummary(bombers, nil, nil, "maj", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(summary, single) {
+		t.Errorf("raids were not resolved alike by any number of workers\nexpected: %+v\nactual: %+v", summary, single)
+	}
+
+	// A cancelled raid covers the raids resolved so far
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s = NewSimulator(WithIterations(100000), WithSeed(1), WithProgress(func(p Progress) {
+		if p.CompletedIterations >= 1000 {
+			cancel()
+		}
+	}))
+	summary, err = s.GetBombingRaidSummaryContext(ctx, bombers, nil, nil, "maj", 0)
+	if err != context.Canceled || !summary.Incomplete || summary.TotalSimulations < 1000 || summary.TotalSimulations >= 100000 {
+		t.Errorf("partial raid summary was not generated correctly\nerror: %v\n%+v", err, summary)
+	}
+
+	// Raids can not be run to a precision
+	s = NewSimulator(WithPrecision(1, 10000))
+	if _, err := s.GetBombingRaidSummary(bombers, nil, nil, "maj", 0); err == nil {
+		t.Errorf("precise bombing raids should be rejected")
+	} else if _, ok := err.(*UnsupportedError); !ok {
+		t.Errorf("expected an UnsupportedError, actual: %v", err)
+	}
+}
+
+func TestBombingRaidValidity(t *testing.T) {
+	values := []struct {
+		game           string
+		bombers        map[string]int
+		escorts        map[string]int
+		interceptors   map[string]int
+		facility       string
+		territoryValue int
+		unsupported    bool
+	}{
+		{"1940", map[string]int{"tac": 1}, nil, nil, "maj", 10, false},
+		{"1940", map[string]int{"bom": 1}, nil, nil, "ind", 10, false},
+		{"1940", map[string]int{"bom": 1}, map[string]int{"tan": 1}, nil, "maj", 10, false},
+		{"1942", map[string]int{"bom": 1}, map[string]int{"fig": 1}, nil, "ind", 10, true},
+		{"deluxe", map[string]int{"lbr": 1}, nil, nil, "ind", 10, true},
+		// Facilities capped by the territory need a territory worth something
+		{"1942", map[string]int{"bom": 1}, nil, nil, "ind", 0, true},
+		{"1941", map[string]int{"bom": 1}, nil, nil, "ind", -2, true},
+	}
+
+	for _, tt := range values {
+		s := NewSimulator(WithGame(tt.game))
+		_, err := s.GetBombingRaidSummary(tt.bombers, tt.escorts, tt.interceptors, tt.facility, tt.territoryValue)
+
+		_, unsupported := err.(*UnsupportedError)
+		_, invalid := err.(*InvalidUnitError)
+		if unsupported != tt.unsupported || invalid == tt.unsupported {
+			t.Errorf("bombing raid was not rejected correctly\ngame: %v\nbombers: %v\nerror: %v", tt.game, tt.bombers, err)
+		}
+	}
+
+	s := NewSimulator(WithIterations(0))
+	if _, err := s.GetBombingRaidSummary(map[string]int{"bom": 1}, nil, nil, "maj", 0); err == nil {
+		t.Errorf("bombing raids should need iterations above 0")
+	}
+
+	s = NewSimulator(WithDice(&scriptedDice{faces: []int{1}}))
+	if _, err := s.GetBombingRaidSummary(map[string]int{"bom": 1}, nil, nil, "maj", 0); err == nil {
+		t.Errorf("bombing raids should need dice able to roll a face")
+	}
+}
@@ -0,0 +1,280 @@
+package oddsengine
+
+import (
+	"math"
+	"sort"
+)
+
+// HitRestriction limits the units that hits may be assigned to
+type HitRestriction int
+
+const (
+	// AnyUnit hits may be assigned to any unit
+	AnyUnit HitRestriction = iota
+
+	// ShipsOnly hits are those of submarines, which only ships may take
+	ShipsOnly
+
+	// NoSubs hits are those of aircraft unable to hit submarines
+	NoSubs
+
+	// AircraftOnly hits are those of AAA, which only aircraft may take
+	AircraftOnly
+
+	// SurfaceShipsOnly hits are those of kamikaze, which only surface ships
+	// may take
+	SurfaceShipsOnly
+)
+
+// CasualtyRequest describes the hits a side of a conflict has to take
+// casualties for. Capital ships have already absorbed the hits they are able
+// to.
+type CasualtyRequest struct {
+	// Formation The units of the side, it must not be modified
+	Formation map[string]int
+
+	// Hits The number of casualties to take
+	Hits int
+
+	// Restriction The units the hits may be assigned to
+	Restriction HitRestriction
+
+	// Enemy The units of the other side, it must not be modified
+	Enemy map[string]int
+
+	// Attacking Whether the side is the attacker
+	Attacking bool
+
+	// Ool The order of loss of the side, holding only the units the hits may
+	// be assigned to
+	Ool []string
+
+	// Units The units of the game being simulated
+	Units Units
+
+	// Power returns the number of hits a formation of the side is expected
+	// to score in a round
+	Power func(formation map[string]int) float64
+}
+
+// CasualtySelector chooses the casualties a side takes. SelectCasualties
+// returns the number of units removed by formation key, any hits left
+// unassigned are taken by the order of loss. Units which may not take the
+// hits are never removed.
+type CasualtySelector interface {
+	SelectCasualties(r CasualtyRequest) map[string]int
+}
+
+// OolSelector takes casualties in the order of loss, the default behaviour of
+// every Simulator.
+type OolSelector struct{}
+
+// SelectCasualties implementing CasualtySelector
+func (OolSelector) SelectCasualties(r CasualtyRequest) map[string]int {
+	remaining := copyFormation(r.Formation)
+	(&Simulator{units: r.Units}).takeCasualties(remaining, r.Hits, r.Ool)
+
+	removed := map[string]int{}
+	for key, n := range r.Formation {
+		if n > remaining[key] {
+			removed[key] = n - remaining[key]
+		}
+	}
+
+	return removed
+}
+
+// GreedySelector takes casualties one hit at a time, each time removing the
+// unit which leaves the side with the most expected hits per round. The
+// cheapest unit is taken when several are worth as much. Reserved units,
+// defenseless transports and AAA are left to the order of loss, so they are
+// still taken last.
+type GreedySelector struct{}
+
+// SelectCasualties implementing CasualtySelector
+func (GreedySelector) SelectCasualties(r CasualtyRequest) map[string]int {
+	remaining := copyFormation(r.Formation)
+	removed := map[string]int{}
+
+	for hit := 0; hit < r.Hits; hit++ {
+		var best string
+		var bestPower float64
+		var bestCost int
+
+		for _, key := range greedyCandidates(remaining, r) {
+			removeUnit(remaining, key)
+			power := r.Power(remaining)
+			remaining[key]++
+
+			cost := r.Units.Find(realAlias(key)).Cost
+			if best == "" || power > bestPower+1e-9 || (math.Abs(power-bestPower) <= 1e-9 && cost < bestCost) {
+				best, bestPower, bestCost = key, power, cost
+			}
+		}
+
+		if best == "" {
+			break
+		}
+
+		removeUnit(remaining, best)
+		removed[best]++
+	}
+
+	return removed
+}
+
+// greedyCandidates returns the sorted keys of the formation which the greedy
+// selector may remove
+func greedyCandidates(f map[string]int, r CasualtyRequest) []string {
+	keys := []string{}
+	for key := range f {
+		unit := r.Units.Find(realAlias(key))
+		if key[0] == '+' || unit.IsAAA || (unit.IsTransport && unit.Defend == 0) || !sliceHasUnit(r.Ool, key) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// removeUnit removes a single unit from the formation
+func removeUnit(f map[string]int, key string) {
+	f[key]--
+	if f[key] <= 0 {
+		delete(f, key)
+	}
+}
+
+// isStaticSelector returns whether the selector follows the order of loss
+func isStaticSelector(selector CasualtySelector) bool {
+	_, static := selector.(OolSelector)
+	return selector == nil || static
+}
+
+// selectCasualties takes num casualties from a side of the conflict, f, using
+// the side's CasualtySelector. The ool holds the units the hits may be
+// assigned to. Returns the IPC value of the casualties taken.
+func (s *Simulator) selectCasualties(attacking bool, f, enemy map[string]int, num int, ool []string, restriction HitRestriction) int {
+	if s.recording() && num > 0 {
+		s.observing.assign(attacking, num, restriction)
+		before := copyFormation(f)
+		defer func() {
+			s.trace.casualties(attacking, num, before, f)
+			s.observing.casualties(attacking, num, before, f)
+		}()
+	}
+
+	if !attacking || s.keepLandUnits <= 0 || num <= 0 {
+		return s.chooseCasualties(attacking, f, enemy, num, ool, restriction)
+	}
+
+	if s.hasUndamagedCapitalShips(f) {
+		num = num - s.damageCapitalShips(f, num)
+	}
+
+	// The land units kept are set aside while the other units take the hits,
+	// they are only taken once no other unit is able to
+	kept := s.keptLandUnits(f, s.keepLandUnits)
+	units := getTotalNumUnits(f)
+	loss := s.chooseCasualties(attacking, f, enemy, num, ool, restriction)
+	num -= units - getTotalNumUnits(f)
+
+	for key, n := range kept {
+		f[key] += n
+	}
+
+	return loss + s.chooseCasualties(attacking, f, enemy, num, ool, restriction)
+}
+
+// chooseCasualties takes the casualties of selectCasualties, by the side's
+// CasualtySelector
+func (s *Simulator) chooseCasualties(attacking bool, f, enemy map[string]int, num int, ool []string, restriction HitRestriction) int {
+	selector, mode := s.defenderSelector, "defend"
+	if attacking {
+		selector, mode = s.attackerSelector, "attack"
+	}
+
+	// The order of loss is followed without building a request
+	if isStaticSelector(selector) || num <= 0 {
+		return s.takeCasualties(f, num, ool)
+	}
+
+	if s.hasUndamagedCapitalShips(f) {
+		num = num - s.damageCapitalShips(f, num)
+	}
+
+	removed := selector.SelectCasualties(CasualtyRequest{
+		Formation:   copyFormation(f),
+		Hits:        num,
+		Restriction: restriction,
+		Enemy:       copyFormation(enemy),
+		Attacking:   attacking,
+		Ool:         ool,
+		Units:       s.units,
+		Power: func(formation map[string]int) float64 {
+			return s.expectedHits(formation, mode)
+		},
+	})
+
+	keys := make([]string, 0, len(removed))
+	for key := range removed {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var loss int
+	for _, key := range keys {
+		n := removed[key]
+		if n > f[key] {
+			n = f[key]
+		}
+		if n > num {
+			n = num
+		}
+		if n <= 0 || !sliceHasUnit(ool, key) {
+			continue
+		}
+
+		f[key] -= n
+		if f[key] == 0 {
+			delete(f, key)
+		}
+		num -= n
+		loss += s.lossValue(s.units.Find(realAlias(key))) * n
+	}
+
+	// Hits left unassigned by the selector are taken in the order of loss
+	return loss + s.takeCasualties(f, num, ool)
+}
+
+// expectedHits returns the number of hits the formation is expected to score
+// in a round, mode being "attack" or "defend". AAA, not firing in the rounds of
+// the conflict, scores none.
+func (s *Simulator) expectedHits(f map[string]int, mode string) (hits float64) {
+	sides := float64(s.dieSides())
+	chance := func(hitValue int) float64 {
+		return math.Min(math.Max(float64(hitValue), 0), sides) / sides
+	}
+
+	rolled := map[string]int{}
+	for key, n := range f {
+		unit := s.units.Find(realAlias(key))
+		if unit.IsAAA {
+			continue
+		}
+
+		if mode == "attack" && unit.MultiRoll > 0 {
+			hits += float64(n) * (1 - math.Pow(1-chance(unit.Attack), float64(unit.MultiRoll)))
+			continue
+		}
+		rolled[key] = n
+	}
+
+	for _, rv := range s.createRollMap(rolled, mode) {
+		hits += float64(rv.num) * chance(rv.hitValue)
+	}
+
+	return hits
+}
@@ -0,0 +1,207 @@
+package oddsengine
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGreedySelector(t *testing.T) {
+	values := []struct {
+		attacking   bool
+		formation   map[string]int
+		hits        int
+		ool         []string
+		casualties  map[string]int
+		description string
+	}{
+		{false, map[string]int{"fig": 1, "bom": 1}, 1, nil, map[string]int{"bom": 1}, "defending bomber taken before the fighter"},
+		{true, map[string]int{"fig": 1, "bom": 1}, 1, nil, map[string]int{"fig": 1}, "attacking fighter taken before the bomber"},
+		{true, map[string]int{"inf": 2, "art": 1}, 1, nil, map[string]int{"inf": 1}, "artillery kept along with the infantry it supports"},
+		{false, map[string]int{"inf": 1, "+inf": 1}, 1, nil, map[string]int{"inf": 1}, "reserved unit kept"},
+		{false, map[string]int{"sub": 1, "fig": 1}, 1, []string{"sub"}, map[string]int{"sub": 1}, "only units able to take the hits removed"},
+		{false, map[string]int{"aaa": 1}, 1, nil, map[string]int{}, "AAA left to the order of loss"},
+	}
+
+	s := NewSimulator(WithCasualtySelectors(GreedySelector{}, GreedySelector{}))
+	for _, tt := range values {
+		f := copyFormation(tt.formation)
+		ool := tt.ool
+		if ool == nil {
+			ool = s.customizeOol(f, f).defender
+		}
+
+		casualties := GreedySelector{}.SelectCasualties(CasualtyRequest{
+			Formation: f,
+			Hits:      tt.hits,
+			Attacking: tt.attacking,
+			Ool:       ool,
+			Units:     s.units,
+			Power: func(formation map[string]int) float64 {
+				if tt.attacking {
+					return s.expectedHits(formation, "attack")
+				}
+				return s.expectedHits(formation, "defend")
+			},
+		})
+		if !reflect.DeepEqual(casualties, tt.casualties) {
+			t.Errorf("%s\nexpected: %v\nactual: %v", tt.description, tt.casualties, casualties)
+		}
+		if !reflect.DeepEqual(f, tt.formation) {
+			t.Errorf("%s\nthe formation was modified: %v", tt.description, f)
+		}
+	}
+}
+
+func TestOolSelector(t *testing.T) {
+	s := NewSimulator()
+	formation := map[string]int{"inf": 2, "art": 1, "fig": 1, "+tan": 1}
+	ool := s.customizeOol(formation, formation).attacker
+
+	for hits := 0; hits <= 6; hits++ {
+		expected := copyFormation(formation)
+		s.takeCasualties(expected, hits, ool)
+
+		casualties := OolSelector{}.SelectCasualties(CasualtyRequest{Formation: formation, Hits: hits, Ool: ool, Units: s.units})
+		actual := copyFormation(formation)
+		for alias, n := range casualties {
+			actual[alias] -= n
+			if actual[alias] == 0 {
+				delete(actual, alias)
+			}
+		}
+
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("casualties did not follow the order of loss for %d hits\nexpected: %v\nactual: %v", hits, expected, actual)
+		}
+	}
+}
+
+// badSelector removes more units than there are hits, along with units which
+// may not take them
+type badSelector struct{}
+
+func (badSelector) SelectCasualties(r CasualtyRequest) map[string]int {
+	return map[string]int{"fig": 5, "sub": 5}
+}
+
+// emptySelector leaves every hit to the order of loss
+type emptySelector struct{}
+
+func (emptySelector) SelectCasualties(r CasualtyRequest) map[string]int {
+	return nil
+}
+
+func TestSelectCasualties(t *testing.T) {
+	values := []struct {
+		selector    CasualtySelector
+		formation   map[string]int
+		hits        int
+		ool         func(s *Simulator) []string
+		remaining   map[string]int
+		ipcLoss     int
+		description string
+	}{
+		{GreedySelector{}, map[string]int{"fig": 1, "bom": 1}, 1, func(s *Simulator) []string { return s.baseOol }, map[string]int{"fig": 1}, 12, "greedy casualty taken"},
+		{GreedySelector{}, map[string]int{"bat": 1, "cru": 1}, 2, func(s *Simulator) []string { return s.ships }, map[string]int{"-bat": 1}, 12, "capital ship damaged before the selector is asked"},
+		{badSelector{}, map[string]int{"fig": 2, "sub": 2}, 2, func(s *Simulator) []string { return s.ships }, map[string]int{"fig": 2}, 12, "removals clamped to the hits and the units able to take them"},
+		{emptySelector{}, map[string]int{"inf": 2, "tan": 1}, 2, func(s *Simulator) []string { return s.baseOol }, map[string]int{"tan": 1}, 6, "unassigned hits taken in the order of loss"},
+	}
+
+	for _, tt := range values {
+		s := NewSimulator(WithCasualtySelectors(nil, tt.selector))
+		f := copyFormation(tt.formation)
+		ipcLoss := s.selectCasualties(false, f, map[string]int{"inf": 1}, tt.hits, tt.ool(s), AnyUnit)
+
+		if !reflect.DeepEqual(f, tt.remaining) || ipcLoss != tt.ipcLoss {
+			t.Errorf("%s\nexpected: %v %d\nactual: %v %d", tt.description, tt.remaining, tt.ipcLoss, f, ipcLoss)
+		}
+	}
+}
+
+func TestCasualtySelectorsConflict(t *testing.T) {
+	attackers := map[string]int{"inf": 4, "tan": 2}
+	defenders := map[string]int{"inf": 2, "fig": 1, "bom": 2}
+
+	static, err := NewSimulator(WithIterations(5000), WithSeed(1)).GetSummary(attackers, defenders)
+	if err != nil {
+		t.Fatal(err)
+	}
+	greedy, err := NewSimulator(WithIterations(5000), WithSeed(1), WithCasualtySelectors(nil, GreedySelector{})).GetSummary(attackers, defenders)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Keeping the fighter, the defence holds more often
+	if greedy.DefenderWinPercentage <= static.DefenderWinPercentage {
+		t.Errorf("greedy defender did not outperform the order of loss\nstatic: %+v\ngreedy: %+v", static, greedy)
+	}
+
+	if _, err := NewSimulator(WithCasualtySelectors(GreedySelector{}, nil)).GetExactSummary(attackers, defenders); err == nil {
+		t.Error("exact summary solved with a casualty selector")
+	}
+	if _, err := NewSimulator(WithCasualtySelectors(OolSelector{}, OolSelector{})).GetExactSummary(attackers, defenders); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestKeepLandUnits(t *testing.T) {
+	values := []struct {
+		keep      int
+		formation map[string]int
+		hits      int
+		remaining map[string]int
+	}{
+		{0, map[string]int{"inf": 2, "art": 1, "fig": 1}, 3, map[string]int{"fig": 1}},
+		{1, map[string]int{"inf": 2, "art": 1, "fig": 1}, 3, map[string]int{"inf": 1}},
+		{1, map[string]int{"inf": 2, "art": 1, "fig": 1}, 4, map[string]int{}},
+		{2, map[string]int{"inf": 2, "art": 1, "fig": 1}, 3, map[string]int{"inf": 1}},
+		{1, map[string]int{"tan": 1, "+art": 1, "bom": 1}, 1, map[string]int{"+art": 1, "bom": 1}},
+		{1, map[string]int{"tan": 1, "+art": 1, "bom": 1}, 2, map[string]int{"+art": 1}},
+	}
+
+	for _, tt := range values {
+		s := NewSimulator(WithKeepLandUnits(tt.keep))
+		f := copyFormation(tt.formation)
+		s.selectCasualties(true, f, map[string]int{"inf": 1}, tt.hits, s.customizeOol(f, nil).attacker, AnyUnit)
+
+		if !reflect.DeepEqual(f, tt.remaining) {
+			t.Errorf("land units were not kept\nkeep: %d\nformation: %v\nhits: %d\nexpected: %v\nactual: %v", tt.keep, tt.formation, tt.hits, tt.remaining, f)
+		}
+	}
+
+	attackers := map[string]int{"inf": 1, "fig": 2}
+	defenders := map[string]int{"inf": 2}
+
+	cost, err := NewSimulator(WithIterations(5000), WithSeed(1)).GetSummary(attackers, defenders)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept, err := NewSimulator(WithIterations(5000), WithSeed(1), WithKeepLandUnits(1)).GetSummary(attackers, defenders)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if kept.TerritoryCapturedPercentage <= cost.TerritoryCapturedPercentage || kept.TerritoryCapturedPercentage > kept.AttackerWinPercentage {
+		t.Errorf("keeping a land unit did not capture the territory more often\ncost: %+v\nkept: %+v", cost, kept)
+	}
+
+	if _, err := NewSimulator(WithKeepLandUnits(1)).GetExactSummary(attackers, defenders); err == nil {
+		t.Error("exact summary solved while keeping land units")
+	}
+
+	// Must take territory keeps a single land unit, leaving the caller's
+	// formation as it was
+	mustTake, err := NewSimulator(WithIterations(5000), WithSeed(1), WithMustTakeTerritory(true)).GetSummary(attackers, defenders)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(mustTake, kept) {
+		t.Errorf("must take territory did not keep a land unit\nexpected: %+v\nactual: %+v", kept, mustTake)
+	}
+	if !reflect.DeepEqual(attackers, map[string]int{"inf": 1, "fig": 2}) {
+		t.Errorf("must take territory changed the attackers\nactual: %v", attackers)
+	}
+	if s := NewSimulator(WithKeepLandUnits(3), WithMustTakeTerritory(true)); s.keepLandUnits != 3 {
+		t.Errorf("must take territory lowered the land units kept to %d", s.keepLandUnits)
+	}
+}
@@ -0,0 +1,105 @@
+package oddsengine
+
+import "math"
+
+// z95 is the standard normal quantile of a two sided 95% confidence interval
+const z95 = 1.959963984540054
+
+// Interval is the standard error and 95% confidence interval of a value in a
+// Summary. Percentages carry their interval as percentages too.
+type Interval struct {
+	// StandardError The standard error of the value
+	StandardError float64 `json:"standardError"`
+
+	// Lower The lower bound of the 95% confidence interval
+	Lower float64 `json:"lower"`
+
+	// Upper The upper bound of the 95% confidence interval
+	Upper float64 `json:"upper"`
+}
+
+// Confidence holds the Interval of each percentage and average in a Summary.
+// Win and draw percentages use a Wilson score interval, the averages use a
+// normal interval.
+type Confidence struct {
+	AverageRounds               Interval `json:"averageRounds"`
+	AttackerWinPercentage       Interval `json:"attackerWinPercentage"`
+	DefenderWinPercentage       Interval `json:"defenderWinPercentage"`
+	DrawPercentage              Interval `json:"drawPercentage"`
+	AttackerRetreatPercentage   Interval `json:"attackerRetreatPercentage"`
+	TerritoryCapturedPercentage Interval `json:"territoryCapturedPercentage"`
+	UnresolvedPercentage        Interval `json:"unresolvedPercentage"`
+	AttackerSubmergedPercentage Interval `json:"attackerSubmergedPercentage"`
+	DefenderSubmergedPercentage Interval `json:"defenderSubmergedPercentage"`
+	AAAHitsAverage              Interval `json:"aaaHitsAverage"`
+	KamikazeHitsAverage         Interval `json:"kamikazeHitsAverage"`
+	AttackerAvgIpcLoss          Interval `json:"attackerAvgIpcLoss"`
+	DefenderAvgIpcLoss          Interval `json:"defenderAvgIpcLoss"`
+	AvgIpcSwing                 Interval `json:"avgIpcSwing"`
+}
+
+// wilsonInterval returns the Interval, as a percentage, of the proportion of
+// successes out of n trials. Unlike the normal approximation the Wilson
+// interval holds up for rates close to 0 or 100 percent.
+func wilsonInterval(successes, n float64) Interval {
+	if n == 0 {
+		return Interval{}
+	}
+
+	p := successes / n
+	center, half := wilson(successes, n)
+
+	return Interval{
+		StandardError: round(math.Sqrt(p*(1-p)/n)*100, 2),
+		Lower:         round(math.Max(center-half, 0)*100, 2),
+		Upper:         round(math.Min(center+half, 1)*100, 2),
+	}
+}
+
+// wilson returns the center and half width of the Wilson score interval of
+// the proportion of successes out of n trials.
+func wilson(successes, n float64) (center, half float64) {
+	p := successes / n
+	z2 := z95 * z95
+	denom := 1 + z2/n
+	center = (p + z2/(2*n)) / denom
+	half = z95 * math.Sqrt(p*(1-p)/n+z2/(4*n*n)) / denom
+	return center, half
+}
+
+// normalInterval returns the Interval of the mean of n samples, given the sum
+// of the samples and the sum of their squares. Every average in a Summary but
+// the IPC swing is of a count, so the lower bound never drops below 0.
+func normalInterval(sum, sumSquares, n float64) Interval {
+	i := signedNormalInterval(sum, sumSquares, n)
+	i.Lower = math.Max(i.Lower, 0)
+	return i
+}
+
+// signedNormalInterval returns the Interval of the mean of n samples which may
+// be negative, like normalInterval otherwise.
+func signedNormalInterval(sum, sumSquares, n float64) Interval {
+	if n == 0 {
+		return Interval{}
+	}
+
+	mean := sum / n
+	if n < 2 {
+		return exactInterval(mean)
+	}
+
+	variance := math.Max((sumSquares-n*mean*mean)/(n-1), 0)
+	se := math.Sqrt(variance / n)
+
+	return Interval{
+		StandardError: round(se, 2),
+		Lower:         round(mean-z95*se, 2),
+		Upper:         round(mean+z95*se, 2),
+	}
+}
+
+// exactInterval returns the Interval of a value known exactly, it carries no
+// error and both bounds are the value itself.
+func exactInterval(v float64) Interval {
+	return Interval{Lower: round(v, 2), Upper: round(v, 2)}
+}
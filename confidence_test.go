@@ -0,0 +1,50 @@
+package oddsengine
+
+import "testing"
+
+func TestWilsonInterval(t *testing.T) {
+	values := []struct {
+		successes float64
+		n         float64
+		expected  Interval
+	}{
+		{0, 0, Interval{}},
+		{50, 100, Interval{StandardError: 5, Lower: 40.38, Upper: 59.62}},
+		{0, 10, Interval{StandardError: 0, Lower: 0, Upper: 27.75}},
+		{599, 1000, Interval{StandardError: 1.55, Lower: 56.83, Upper: 62.89}},
+	}
+
+	for _, tt := range values {
+		if actual := wilsonInterval(tt.successes, tt.n); actual != tt.expected {
+			t.Errorf("wilson interval of %v/%v was not calculated correctly\nexpected: %+v\nactual: %+v", tt.successes, tt.n, tt.expected, actual)
+		}
+	}
+}
+
+func TestNormalInterval(t *testing.T) {
+	values := []struct {
+		sum        float64
+		sumSquares float64
+		n          float64
+		expected   Interval
+	}{
+		{0, 0, 0, Interval{}},
+		{3, 9, 1, Interval{Lower: 3, Upper: 3}},
+		// The samples 1, 2, 3 and 4
+		{10, 30, 4, Interval{StandardError: 0.65, Lower: 1.23, Upper: 3.77}},
+		// The samples 0, 0, 0 and 1, whose interval would drop below 0
+		{1, 1, 4, Interval{StandardError: 0.25, Lower: 0, Upper: 0.74}},
+	}
+
+	for _, tt := range values {
+		if actual := normalInterval(tt.sum, tt.sumSquares, tt.n); actual != tt.expected {
+			t.Errorf("normal interval of %v samples was not calculated correctly\nexpected: %+v\nactual: %+v", tt.n, tt.expected, actual)
+		}
+	}
+
+	// The IPC swing may well be negative
+	expected := Interval{StandardError: 0.25, Lower: -0.24, Upper: 0.74}
+	if actual := signedNormalInterval(1, 1, 4); actual != expected {
+		t.Errorf("signed normal interval was not calculated correctly\nexpected: %+v\nactual: %+v", expected, actual)
+	}
+}
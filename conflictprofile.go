@@ -1,5 +1,25 @@
 package oddsengine
 
+// The possible outcomes of a conflict
+const (
+	// DefenderWin the defender destroyed every attacking unit
+	DefenderWin = -1
+
+	// Draw either both sides were destroyed, or neither side could hit the
+	// other
+	Draw = 0
+
+	// AttackerWin the attacker destroyed every defending unit
+	AttackerWin = 1
+
+	// AttackerRetreat the attacker retreated according to its RetreatPolicy
+	AttackerRetreat = 2
+
+	// Unresolved the conflict was still going when the maximum number of
+	// rounds had been fought
+	Unresolved = 3
+)
+
 // ConflictProfile is a struct representing the outcome of a single conflict
 type ConflictProfile struct {
 	// Rounds is the number of rounds a conflict took
@@ -11,6 +31,9 @@ type ConflictProfile struct {
 	// AttackerHits is number of losses by round for the defender
 	AttackerHits []int
 
+	// RoundStates is the state of the conflict at the end of each round
+	RoundStates []RoundState
+
 	// The number of IPC's that the attacker lost in the conflict
 	AttackerIpcLoss int
 
@@ -23,13 +46,33 @@ type ConflictProfile struct {
 	// Number of Defending Units Remaining at the end of the Conflict
 	DefenderUnitsRemaining []map[string]int
 
+	// Attacking Units that submerged during the Conflict
+	AttackerUnitsSubmerged []map[string]int
+
+	// Defending Units that submerged during the Conflict
+	DefenderUnitsSubmerged []map[string]int
+
+	// Attacking ships that bombarded the territory, they can not be hit and
+	// survive the Conflict whatever its outcome
+	AttackerUnitsBombarding []map[string]int
+
+	// Attacking units of the fleet of an amphibious assault left in the sea
+	// zone without bombarding, they survive the Conflict whatever its outcome
+	AttackerUnitsAtSea []map[string]int
+
 	// AAA Hits represent the number of AAA hits for the conflict
 	AAAHits int
 
 	// AAA Hits represent the number of Kamikaze hits for the conflict
 	KamikazeHits int
 
+	// TerritoryCaptured is true when the attacker won with land units left to
+	// take the territory
+	TerritoryCaptured bool
+
 	// Outcome represents the status of the conflict after the fact.
+	//  3: Unresolved
+	//  2: Attacker Retreat
 	//  1: Attacker Victory
 	//  0: Draw
 	// -1: Defender Victory
@@ -61,15 +61,16 @@ func TestConflictResolution(t *testing.T) {
 			"1940",
 			1,
 			ConflictProfile{
-				Rounds:                 3,
-				AttackerHits:           []int{4, 0, 1},
-				DefenderHits:           []int{4, 1, 2},
-				AttackerIpcLoss:        44,
-				DefenderIpcLoss:        17,
-				AAAHits:                0,
-				KamikazeHits:           0,
-				DefenderUnitsRemaining: formationToSortedSlice(map[string]int{"mec": 1, "tan": 1}),
-				Outcome:                -1,
+				Rounds:                  3,
+				AttackerHits:            []int{4, 0, 1},
+				DefenderHits:            []int{4, 1, 2},
+				AttackerIpcLoss:         44,
+				DefenderIpcLoss:         17,
+				AAAHits:                 0,
+				KamikazeHits:            0,
+				DefenderUnitsRemaining:  formationToSortedSlice(map[string]int{"mec": 1, "tan": 1}),
+				AttackerUnitsBombarding: formationToSortedSlice(map[string]int{"bat": 2}),
+				Outcome:                 -1,
 			},
 			false,
 		},
@@ -88,6 +89,7 @@ func TestConflictResolution(t *testing.T) {
 				AttackerUnitsRemaining: formationToSortedSlice(map[string]int{"inf": 3, "fig": 1, "tac": 2, "bom": 1}),
 				AAAHits:                2,
 				KamikazeHits:           0,
+				TerritoryCaptured:      true,
 				Outcome:                1,
 			},
 			false,
@@ -180,6 +182,7 @@ func TestConflictResolution(t *testing.T) {
 				AttackerUnitsRemaining: formationToSortedSlice(map[string]int{"inf": 2, "fig": 1, "tac": 2}),
 				AAAHits:                2,
 				KamikazeHits:           0,
+				TerritoryCaptured:      true,
 				Outcome:                1,
 			},
 			false,
@@ -234,6 +237,7 @@ func TestConflictResolution(t *testing.T) {
 				AttackerUnitsRemaining: formationToSortedSlice(map[string]int{"inf": 2, "art": 3}),
 				AAAHits:                0,
 				KamikazeHits:           0,
+				TerritoryCaptured:      true,
 				Outcome:                1,
 			},
 			false,
@@ -253,6 +257,7 @@ func TestConflictResolution(t *testing.T) {
 				AttackerUnitsRemaining: formationToSortedSlice(map[string]int{"+mec": 1}),
 				AAAHits:                0,
 				KamikazeHits:           0,
+				TerritoryCaptured:      true,
 				Outcome:                1,
 			},
 			false,
@@ -454,6 +459,7 @@ func TestConflictResolution(t *testing.T) {
 				AttackerUnitsRemaining: formationToSortedSlice(map[string]int{"inf": 1}),
 				AAAHits:                3,
 				KamikazeHits:           0,
+				TerritoryCaptured:      true,
 				Outcome:                1,
 			},
 			false,
@@ -518,20 +524,21 @@ func TestConflictResolution(t *testing.T) {
 		},
 	}
 	for _, tt := range values {
-		SetGame(tt.game)
-		SetMustTakeTerritory(tt.mustTakeTerritory)
-		if mustTakeTerritory {
-			reserveHighestValueLandUnit(tt.attackers)
+		s := NewSimulator(WithGame(tt.game), WithMustTakeTerritory(tt.mustTakeTerritory))
+
+		ool := s.customizeOol(tt.attackers, tt.defenders)
+		s.rng = rand.New(rand.NewSource(tt.randSeed))
+		p := s.resolveConflict(tt.attackers, tt.defenders, ool)
+
+		// The state of every round is checked by TestRoundStates, here it only
+		// needs to cover every round fought
+		if len(p.RoundStates) != p.Rounds {
+			t.Errorf("Round states do not cover every round\nrounds: %d\nstates: %+v", p.Rounds, p.RoundStates)
 		}
+		p.RoundStates = nil
 
-		ool := customizeOol(tt.attackers, tt.defenders)
-		rand.Seed(tt.randSeed)
-		p := resolveConflict(tt.attackers, tt.defenders, ool)
 		if !reflect.DeepEqual(p, &tt.outcome) {
 			t.Errorf("Conflict Profile Doesn't Match\nexpected: %+v\nactual: %+v", tt.outcome, *p)
 		}
 	}
-
-	// Reset the game back to 1940
-	SetGame("1940")
 }
@@ -0,0 +1,25 @@
+package oddsengine
+
+// Dice is a source of dice rolls for the engine. Every die the engine rolls
+// goes through Roll, allowing scripted dice, dice decks or any other source of
+// randomness to be plugged into a Simulator with WithDice.
+//
+// A Simulator resolves conflicts on many goroutines at once, so a Dice must be
+// safe for concurrent use. A Dice relying on the order of its rolls should be
+// paired with WithWorkers(1).
+type Dice interface {
+	// Roll rolls num dice with the given number of sides and returns the
+	// number of dice that scored a hit, rolling hitValue or less.
+	Roll(num, hitValue, sides int) (hits int)
+}
+
+// FaceDice is a Dice that is also able to roll the face of a single die, used
+// where the value rolled matters rather than whether it hit, such as the
+// damage of a strategic bombing raid.
+type FaceDice interface {
+	Dice
+
+	// Face rolls a single die with the given number of sides, returning the
+	// value rolled from 1 to sides.
+	Face(sides int) int
+}
@@ -0,0 +1,81 @@
+package oddsengine
+
+import (
+	"sync"
+	"testing"
+)
+
+// scriptedDice rolls a fixed sequence of faces, looping back to the start once
+// the sequence runs out.
+type scriptedDice struct {
+	faces []int
+	next  int
+}
+
+func (d *scriptedDice) Roll(num, hitValue, sides int) (hits int) {
+	for i := 0; i < num; i++ {
+		if d.faces[d.next%len(d.faces)] <= hitValue {
+			hits++
+		}
+		d.next++
+	}
+	return hits
+}
+
+// countingDice counts every die rolled, hitting with every one of them.
+type countingDice struct {
+	mu    sync.Mutex
+	rolls int
+}
+
+func (d *countingDice) Roll(num, hitValue, sides int) (hits int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.rolls += num
+	return num
+}
+
+func TestScriptedDice(t *testing.T) {
+	values := []struct {
+		faces    []int
+		outcome  float64
+		rounds   float64
+		attacker float64
+	}{
+		// The attacker rolls first, hitting on a 1
+		{[]int{1, 6}, 100, 1, 0},
+		{[]int{6, 2}, 0, 1, 3},
+		{[]int{1, 2}, 0, 1, 3},
+		{[]int{6, 6, 6, 6, 1, 6}, 100, 3, 0},
+	}
+
+	for _, tt := range values {
+		s := NewSimulator(WithDice(&scriptedDice{faces: tt.faces}), WithWorkers(1), WithIterations(1))
+		summary, err := s.GetSummary(map[string]int{"inf": 1}, map[string]int{"inf": 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if summary.AttackerWinPercentage != tt.outcome || summary.AverageRounds != tt.rounds || summary.AttackerAvgIpcLoss != tt.attacker {
+			t.Errorf("scripted dice did not resolve the conflict\nfaces: %v\nactual: %+v", tt.faces, summary)
+		}
+	}
+}
+
+func TestConcurrentDice(t *testing.T) {
+	d := &countingDice{}
+	s := NewSimulator(WithDice(d), WithIterations(1000))
+	summary, err := s.GetSummary(map[string]int{"inf": 2, "tan": 1}, map[string]int{"inf": 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every die hits, each conflict is decided in a single round of 5 dice
+	if d.rolls != 5000 || summary.AverageRounds != 1 {
+		t.Errorf("custom dice were not rolled for every die\nrolls: %v\nsummary: %+v", d.rolls, summary)
+	}
+
+	if _, err := s.GetExactSummary(map[string]int{"inf": 1}, map[string]int{"inf": 1}); err == nil {
+		t.Errorf("exact summary should be unsupported with custom dice")
+	}
+}
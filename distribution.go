@@ -0,0 +1,84 @@
+package oddsengine
+
+import "sort"
+
+// Distribution is how a value of the conflicts in a Summary is spread. The
+// histogram maps each value to the number of conflicts it came up in, and the
+// percentiles are the values that 10, 50 and 90 percent of the conflicts did
+// not exceed.
+type Distribution struct {
+	// Histogram The number of conflicts by value
+	Histogram map[int]int `json:"histogram"`
+
+	// P10 The 10th percentile of the value
+	P10 int `json:"p10"`
+
+	// P50 The median of the value
+	P50 int `json:"p50"`
+
+	// P90 The 90th percentile of the value
+	P90 int `json:"p90"`
+}
+
+// Distributions holds the Distribution of each of the values averaged in a
+// Summary.
+type Distributions struct {
+	Rounds          Distribution `json:"rounds"`
+	AAAHits         Distribution `json:"aaaHits"`
+	KamikazeHits    Distribution `json:"kamikazeHits"`
+	AttackerIpcLoss Distribution `json:"attackerIpcLoss"`
+	DefenderIpcLoss Distribution `json:"defenderIpcLoss"`
+	IpcSwing        Distribution `json:"ipcSwing"`
+}
+
+// distribution creates the Distribution of a histogram
+func distribution(histogram map[int]int) Distribution {
+	d := Distribution{Histogram: copyHistogram(histogram)}
+
+	values := make([]int, 0, len(histogram))
+	var n int
+	for value, count := range histogram {
+		values = append(values, value)
+		n += count
+	}
+	if n == 0 {
+		return d
+	}
+	sort.Ints(values)
+
+	d.P10 = percentile(histogram, values, n, 10)
+	d.P50 = percentile(histogram, values, n, 50)
+	d.P90 = percentile(histogram, values, n, 90)
+
+	return d
+}
+
+// percentile returns the nearest rank percentile p of the n values counted in
+// the histogram, whose values are passed in sorted
+func percentile(histogram map[int]int, values []int, n, p int) int {
+	// The rank is rounded up, so that the percentile is never below p
+	rank := (p*n + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+
+	var seen int
+	for _, value := range values {
+		seen += histogram[value]
+		if seen >= rank {
+			return value
+		}
+	}
+
+	return values[len(values)-1]
+}
+
+// copyHistogram returns a copy of the histogram
+func copyHistogram(histogram map[int]int) map[int]int {
+	c := make(map[int]int, len(histogram))
+	for value, count := range histogram {
+		c[value] = count
+	}
+
+	return c
+}
@@ -0,0 +1,38 @@
+package oddsengine
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDistribution(t *testing.T) {
+	values := []struct {
+		histogram map[int]int
+		expected  Distribution
+	}{
+		{map[int]int{}, Distribution{Histogram: map[int]int{}}},
+		{map[int]int{5: 1}, Distribution{Histogram: map[int]int{5: 1}, P10: 5, P50: 5, P90: 5}},
+		// Usually free, but sometimes a disaster
+		{map[int]int{0: 8, 30: 2}, Distribution{Histogram: map[int]int{0: 8, 30: 2}, P10: 0, P50: 0, P90: 30}},
+		{map[int]int{1: 1, 2: 1, 3: 1, 4: 1, 5: 1, 6: 1, 7: 1, 8: 1, 9: 1, 10: 1}, Distribution{
+			Histogram: map[int]int{1: 1, 2: 1, 3: 1, 4: 1, 5: 1, 6: 1, 7: 1, 8: 1, 9: 1, 10: 1},
+			P10:       1,
+			P50:       5,
+			P90:       9,
+		}},
+		{map[int]int{-6: 1, 0: 2, 12: 1}, Distribution{Histogram: map[int]int{-6: 1, 0: 2, 12: 1}, P10: -6, P50: 0, P90: 12}},
+	}
+
+	for _, tt := range values {
+		actual := distribution(tt.histogram)
+		if !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("distribution was not created correctly\nexpected: %+v\nactual: %+v", tt.expected, actual)
+		}
+
+		// The distribution must not share its histogram with the accumulator
+		actual.Histogram[100]++
+		if _, ok := tt.histogram[100]; ok {
+			t.Errorf("distribution shares its histogram\n%v", tt.histogram)
+		}
+	}
+}
@@ -10,3 +10,14 @@ type InvalidUnitError struct {
 func (i InvalidUnitError) Error() string {
 	return i.s
 }
+
+// UnsupportedError represents a conflict, or configuration, that the engine is
+// unable to calculate.
+type UnsupportedError struct {
+	s string
+}
+
+// Error returns the string form of the error
+func (u UnsupportedError) Error() string {
+	return u.s
+}
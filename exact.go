@@ -0,0 +1,730 @@
+package oddsengine
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxExactStates is the largest number of battle states the exact solver will
+// walk before giving up. Battles larger than this should be simulated.
+const maxExactStates = 1 << 20
+
+// exactOutcome holds the exact probabilities and expected values of a conflict
+// continuing from a particular battle state.
+type exactOutcome struct {
+	attackerWin       float64
+	defenderWin       float64
+	draw              float64
+	attackerRetreat   float64
+	territoryCaptured float64
+	rounds            float64
+	attackerIpcLoss   float64
+	defenderIpcLoss   float64
+	aaaHits           float64
+	kamikazeHits      float64
+}
+
+// exactBranch is one possible result of a step within a round. p is the
+// probability of reaching the branch. The losses and hits are weighted by p so
+// that branches ending in the same state can be merged by adding them up.
+type exactBranch struct {
+	p               float64
+	attackers       map[string]int
+	defenders       map[string]int
+	attackerIpcLoss float64
+	defenderIpcLoss float64
+	aaaHits         float64
+	kamikazeHits    float64
+
+	// bombard is the distribution of the offshore bombardment hits which are
+	// assigned along with the attacker's standard hits of the first round
+	bombard []float64
+
+	// attackerCanSuprise and defenderCanSuprise record whether the subs
+	// already fired a suprise attack this round
+	attackerCanSuprise bool
+	defenderCanSuprise bool
+
+	// attackersKey and defendersKey cache the formationKey of each side, and
+	// stateKey the resulting key of the state
+	attackersKey string
+	defendersKey string
+	stateKey     string
+}
+
+// key returns the state key of the branch after the first round
+func (b *exactBranch) key() string {
+	if b.stateKey != "" {
+		return b.stateKey
+	}
+	if b.attackersKey == "" {
+		b.attackersKey = formationKey(b.attackers)
+	}
+	if b.defendersKey == "" {
+		b.defendersKey = formationKey(b.defenders)
+	}
+
+	b.stateKey = b.attackersKey + "|" + b.defendersKey + "|false"
+	return b.stateKey
+}
+
+// exactTrial is a number of dice which each score a hit with probability p
+type exactTrial struct {
+	p float64
+	n int
+}
+
+// exactSolver walks the state space of a single conflict. Each state is the
+// remaining attackers and defenders, and whether the first round, with its
+// kamikaze, AAA and bombard, is still to be fought.
+type exactSolver struct {
+	s     *Simulator
+	ool   *conflictOol
+	sides float64
+	memo  map[string]*exactOutcome
+}
+
+// GetExactSummary returns a summary of the conflict using the default
+// Simulator. See Simulator.GetExactSummary
+func GetExactSummary(attackers, defenders map[string]int) (*Summary, error) {
+	return defaultSimulator.GetExactSummary(attackers, defenders)
+}
+
+// GetExactSummary calculates the exact odds of a conflict instead of
+// estimating them through simulation. Every state the battle may pass through
+// is visited, using the binomial distribution of the hits each side can roll
+// from that state. The summary contains exact probabilities and expected
+// values; FirstRoundResults and the remaining units are not calculated.
+func (s *Simulator) GetExactSummary(attackers, defenders map[string]int) (*Summary, error) {
+	var err error
+
+	err = s.checkUnitValidity(attackers)
+	if err != nil {
+		return &Summary{}, err
+	}
+
+	err = s.checkUnitValidity(defenders)
+	if err != nil {
+		return &Summary{}, err
+	}
+
+	if s.lowLuck {
+		return &Summary{}, &UnsupportedError{"Low Luck conflicts cannot be solved exactly"}
+	}
+
+	if s.dice != nil {
+		return &Summary{}, &UnsupportedError{"Conflicts rolling custom dice cannot be solved exactly"}
+	}
+
+	// The number of rounds fought is not part of the battle state
+	if s.retreat.AfterRounds > 0 {
+		return &Summary{}, &UnsupportedError{"Retreating after a number of rounds cannot be solved exactly"}
+	}
+	if s.maxRounds > 0 {
+		return &Summary{}, &UnsupportedError{"Limiting the number of rounds cannot be solved exactly"}
+	}
+	if s.attackerSubmerge != (SubmergePolicy{}) || s.defenderSubmerge != (SubmergePolicy{}) {
+		return &Summary{}, &UnsupportedError{"Submerging submarines cannot be solved exactly"}
+	}
+	if !isStaticSelector(s.attackerSelector) || !isStaticSelector(s.defenderSelector) {
+		return &Summary{}, &UnsupportedError{"Casualty selectors other than the order of loss cannot be solved exactly"}
+	}
+	if s.keepLandUnits > 0 {
+		return &Summary{}, &UnsupportedError{"Keeping land units alive cannot be solved exactly"}
+	}
+	if hasCarrierBasedAircraft(attackers) || hasCarrierBasedAircraft(defenders) {
+		return &Summary{}, &UnsupportedError{"Carrier based aircraft cannot be solved exactly"}
+	}
+
+	e := &exactSolver{
+		s:     s,
+		ool:   s.customizeOol(attackers, defenders),
+		sides: float64(s.dieSides()),
+		memo:  map[string]*exactOutcome{},
+	}
+
+	o, err := e.solve(copyFormation(attackers), copyFormation(defenders), true, stateKey(attackers, defenders, true))
+	if err != nil {
+		return &Summary{}, err
+	}
+
+	// The territory's value is gained whenever it is captured
+	swing := o.defenderIpcLoss - o.attackerIpcLoss + o.territoryCaptured*float64(s.territoryValue)
+
+	return &Summary{
+		Exact:                       true,
+		AverageRounds:               round(o.rounds, 2),
+		AttackerWinPercentage:       round(o.attackerWin*100, 2),
+		DefenderWinPercentage:       round(o.defenderWin*100, 2),
+		DrawPercentage:              round(o.draw*100, 2),
+		AttackerRetreatPercentage:   round(o.attackerRetreat*100, 2),
+		TerritoryCapturedPercentage: round(o.territoryCaptured*100, 2),
+		AAAHitsAverage:              round(o.aaaHits, 2),
+		KamikazeHitsAverage:         round(o.kamikazeHits, 2),
+		AttackerAvgIpcLoss:          round(o.attackerIpcLoss, 2),
+		DefenderAvgIpcLoss:          round(o.defenderIpcLoss, 2),
+		AvgIpcSwing:                 round(swing, 2),
+		Confidence: Confidence{
+			AverageRounds:               exactInterval(o.rounds),
+			AttackerWinPercentage:       exactInterval(o.attackerWin * 100),
+			DefenderWinPercentage:       exactInterval(o.defenderWin * 100),
+			DrawPercentage:              exactInterval(o.draw * 100),
+			AttackerRetreatPercentage:   exactInterval(o.attackerRetreat * 100),
+			TerritoryCapturedPercentage: exactInterval(o.territoryCaptured * 100),
+			AAAHitsAverage:              exactInterval(o.aaaHits),
+			KamikazeHitsAverage:         exactInterval(o.kamikazeHits),
+			AttackerAvgIpcLoss:          exactInterval(o.attackerIpcLoss),
+			DefenderAvgIpcLoss:          exactInterval(o.defenderIpcLoss),
+			AvgIpcSwing:                 exactInterval(swing),
+		},
+	}, nil
+}
+
+// solve returns the outcome of the conflict continuing from the passed in
+// state, identified by its stateKey. The formations are never modified.
+func (e *exactSolver) solve(a, d map[string]int, firstRound bool, key string) (*exactOutcome, error) {
+	if o, ok := e.memo[key]; ok {
+		return o, nil
+	}
+
+	if len(e.memo) >= maxExactStates {
+		return nil, &UnsupportedError{fmt.Sprintf("Conflict has more than %d states to solve exactly", maxExactStates)}
+	}
+
+	if e.s.isResolved(a, d) {
+		o := e.resolvedOutcome(a, d)
+		e.memo[key] = o
+		return o, nil
+	}
+
+	// The attacker may retreat from any state after the first round. Any
+	// number of rounds will do here as AfterRounds is never set.
+	if !firstRound && e.s.shouldRetreat(a, d, 1) {
+		o := &exactOutcome{attackerRetreat: 1}
+		e.memo[key] = o
+		return o, nil
+	}
+
+	// Defenseless transports left on their own are all taken without a
+	// roll, just like resolveConflict.
+	if e.s.hasOnlyDefenselessTransports(a) && !e.s.conflictIsAutoKill(d, a, firstRound) {
+		loss := e.s.takeCasualties(copyFormation(a), getTotalNumUnits(a), e.ool.attacker)
+		o := e.resolvedOutcome(map[string]int{}, d)
+		o.attackerIpcLoss = float64(loss)
+		e.memo[key] = o
+		return o, nil
+	}
+
+	// Defenders unable to defend are all taken without a roll by attackers
+	// able to fire, just like resolveConflict. Neither side is able to hit
+	// the other otherwise, which is a draw.
+	if e.s.conflictIsAutoKill(d, a, firstRound) {
+		if !e.s.canScoreHits(a) {
+			o := e.resolvedOutcome(a, d)
+			e.memo[key] = o
+			return o, nil
+		}
+
+		remaining := copyFormation(d)
+		loss := e.s.takeCasualties(remaining, getTotalNumUnits(remaining), e.ool.defender)
+		o := e.resolvedOutcome(a, remaining)
+		o.defenderIpcLoss = float64(loss)
+		e.memo[key] = o
+		return o, nil
+	}
+
+	o := &exactOutcome{}
+	var stay float64
+
+	for _, b := range e.round(a, d, firstRound) {
+		// A round where nobody is hit leaves the battle where it was. We
+		// account for it once the rest of the transitions are known.
+		if !firstRound && b.key() == key {
+			stay += b.p
+			continue
+		}
+
+		next, err := e.solve(b.attackers, b.defenders, false, b.key())
+		if err != nil {
+			return nil, err
+		}
+
+		o.attackerWin += b.p * next.attackerWin
+		o.defenderWin += b.p * next.defenderWin
+		o.draw += b.p * next.draw
+		o.attackerRetreat += b.p * next.attackerRetreat
+		o.territoryCaptured += b.p * next.territoryCaptured
+		o.rounds += b.p * (1 + next.rounds)
+		o.attackerIpcLoss += b.attackerIpcLoss + b.p*next.attackerIpcLoss
+		o.defenderIpcLoss += b.defenderIpcLoss + b.p*next.defenderIpcLoss
+		o.aaaHits += b.aaaHits + b.p*next.aaaHits
+		o.kamikazeHits += b.kamikazeHits + b.p*next.kamikazeHits
+	}
+
+	// If neither side is ever able to hit the other, the conflict can never
+	// be resolved and we call it a draw.
+	if 1-stay < 1e-12 {
+		o = e.resolvedOutcome(a, d)
+		e.memo[key] = o
+		return o, nil
+	}
+
+	// Staying in the same state costs a round every time it happens. Solving
+	// the state's own equation for its value gives us the division below.
+	o.rounds += stay
+	o.attackerWin /= 1 - stay
+	o.defenderWin /= 1 - stay
+	o.draw /= 1 - stay
+	o.attackerRetreat /= 1 - stay
+	o.territoryCaptured /= 1 - stay
+	o.rounds /= 1 - stay
+	o.attackerIpcLoss /= 1 - stay
+	o.defenderIpcLoss /= 1 - stay
+	o.aaaHits /= 1 - stay
+	o.kamikazeHits /= 1 - stay
+
+	e.memo[key] = o
+	return o, nil
+}
+
+// round returns every possible result of a single round of the conflict,
+// merged by the state they end in. The steps of the round mirror
+// resolveConflict exactly.
+func (e *exactSolver) round(a, d map[string]int, firstRound bool) []*exactBranch {
+	s := e.s
+	branches := []*exactBranch{{p: 1, attackers: a, defenders: d, bombard: []float64{1}}}
+
+	if firstRound {
+		branches = e.expand(branches, func(b *exactBranch) []*exactBranch {
+			var next []*exactBranch
+			defenders := copyFormation(b.defenders)
+			deleteUnitFromFormation(defenders, "kam")
+
+			dist := e.distribution(e.unitSliceTrials(b.defenders, []string{"kam"}, "defend"))
+			for hits, q := range dist {
+				attackers := copyFormation(b.attackers)
+				loss := s.takeCasualties(attackers, hits, s.surfaceShips)
+				next = append(next, &exactBranch{
+					p:               q,
+					attackers:       attackers,
+					defenders:       defenders,
+					attackerIpcLoss: q * float64(loss),
+					kamikazeHits:    q * float64(hits),
+				})
+			}
+			return next
+		})
+
+		branches = e.expand(branches, func(b *exactBranch) []*exactBranch {
+			var next []*exactBranch
+			dist := e.distribution(e.rollMapTrials(s.getAAARollMap(b.attackers, b.defenders)))
+			for hits, q := range dist {
+				attackers := copyFormation(b.attackers)
+				loss := s.takeCasualties(attackers, hits, s.aircraft)
+
+				n := &exactBranch{
+					p:               q,
+					attackers:       attackers,
+					defenders:       b.defenders,
+					attackerIpcLoss: q * float64(loss),
+					aaaHits:         q * float64(hits),
+					bombard:         []float64{1},
+				}
+
+				// The bombard hits are added to the standard attacking hits,
+				// the ships leave the formation before they can be hit.
+				if s.canBombard(attackers) {
+					n.bombard = e.distribution(e.unitSliceTrials(attackers, s.bombardShips, "attack"))
+					for _, ship := range s.bombardShips {
+						deleteUnitFromFormation(attackers, ship)
+					}
+				}
+				next = append(next, n)
+			}
+			return next
+		})
+	}
+
+	// Submarine suprise attacks. The casualties of each side depend only on
+	// the other side's rolls, so the results are combined afterwards.
+	branches = e.expand(branches, func(b *exactBranch) []*exactBranch {
+		attackerCanSuprise := s.canSupriseAttack(b.attackers, b.defenders)
+		defenderCanSuprise := s.canSupriseAttack(b.defenders, b.attackers)
+
+		attackerDist, defenderDist := []float64{1}, []float64{1}
+		if attackerCanSuprise {
+			attackerDist = e.distribution(e.unitSliceTrials(b.attackers, s.subs, "attack"))
+		}
+		if defenderCanSuprise {
+			defenderDist = e.distribution(e.unitSliceTrials(b.defenders, s.subs, "defend"))
+		}
+
+		defenderFates := e.fates(b.defenders, [][]float64{attackerDist}, [][]string{s.ships})
+		attackerFates := e.fates(b.attackers, [][]float64{defenderDist}, [][]string{s.ships})
+
+		next := combineFates(attackerFates, defenderFates)
+		for _, n := range next {
+			n.bombard = b.bombard
+			n.attackerCanSuprise = attackerCanSuprise
+			n.defenderCanSuprise = defenderCanSuprise
+		}
+		return next
+	})
+
+	// Standard combat.
+	branches = e.expand(branches, func(b *exactBranch) []*exactBranch {
+		attackers, defenders := b.attackers, b.defenders
+
+		attackerRollMap := s.createRollMap(attackers, "attack")
+		defenderRollMap := s.createRollMap(defenders, "defend")
+		defenderRollMap.RemoveUnits(s.units, defenders, []string{"aaa", "raaa", "aag"}, "defend")
+
+		if b.attackerCanSuprise {
+			attackerRollMap.RemoveUnits(s.units, attackers, s.subs, "attack")
+		}
+		if b.defenderCanSuprise {
+			defenderRollMap.RemoveUnits(s.units, defenders, s.subs, "defend")
+		}
+
+		attackerAircraft, attackerSubs := []float64{1}, []float64{1}
+		if !s.hasOnlySubs(defenders) || hasUnit(attackers, "des") {
+			attackerAircraft = e.distribution(e.unitSliceTrials(attackers, s.aircraft, "attack"))
+		}
+		attackerRollMap.RemoveUnits(s.units, attackers, s.aircraft, "attack")
+
+		attackingAircraftOol := e.ool.defender
+		if s.hasLimitedAircraft(attackers, defenders) {
+			attackingAircraftOol = e.ool.defenderNoSub
+		}
+
+		if s.hasSub(attackers) && !b.attackerCanSuprise {
+			attackerSubs = e.distribution(e.unitSliceTrials(attackers, s.subs, "attack"))
+			attackerRollMap.RemoveUnits(s.units, attackers, s.subs, "attack")
+		}
+		attackerHits := convolve(b.bombard, e.distribution(e.rollMapTrials(attackerRollMap)))
+
+		defenderAircraft, defenderSubs := []float64{1}, []float64{1}
+		if !s.hasOnlySubs(attackers) || hasUnit(defenders, "des") {
+			defenderAircraft = e.distribution(e.unitSliceTrials(defenders, s.aircraft, "defend"))
+		}
+		defenderRollMap.RemoveUnits(s.units, defenders, s.aircraft, "defend")
+
+		defendingAircraftOol := e.ool.attacker
+		if s.hasLimitedAircraft(defenders, attackers) {
+			defendingAircraftOol = e.ool.attackerNoSub
+		}
+
+		if s.hasSub(defenders) && !b.defenderCanSuprise {
+			defenderSubs = e.distribution(e.unitSliceTrials(defenders, s.subs, "defend"))
+			defenderRollMap.RemoveUnits(s.units, defenders, s.subs, "defend")
+		}
+		defenderHits := e.distribution(e.rollMapTrials(defenderRollMap))
+
+		defenderFates := e.fates(defenders,
+			[][]float64{attackerSubs, attackerAircraft, attackerHits},
+			[][]string{s.ships, attackingAircraftOol, e.ool.defender},
+		)
+		attackerFates := e.fates(attackers,
+			[][]float64{defenderSubs, defenderAircraft, defenderHits},
+			[][]string{s.ships, defendingAircraftOol, e.ool.attacker},
+		)
+
+		return combineFates(attackerFates, defenderFates)
+	})
+
+	return branches
+}
+
+// expand replaces every branch with the branches returned by the step,
+// weighting them by the probability of the original branch, and merges the
+// results ending in the same state.
+func (e *exactSolver) expand(branches []*exactBranch, step func(*exactBranch) []*exactBranch) []*exactBranch {
+	merged := map[string]*exactBranch{}
+	var keys []string
+
+	for _, b := range branches {
+		for _, n := range step(b) {
+			if n.p == 0 {
+				continue
+			}
+
+			n.attackerIpcLoss = b.attackerIpcLoss*n.p + b.p*n.attackerIpcLoss
+			n.defenderIpcLoss = b.defenderIpcLoss*n.p + b.p*n.defenderIpcLoss
+			n.aaaHits = b.aaaHits*n.p + b.p*n.aaaHits
+			n.kamikazeHits = b.kamikazeHits*n.p + b.p*n.kamikazeHits
+			n.p = b.p * n.p
+
+			// Branches waiting on bombard hits can't be merged with others,
+			// their distribution is still to be rolled. Neither can branches
+			// whose subs have already fired.
+			key := n.key() + distributionKey(n.bombard) +
+				"|" + strconv.FormatBool(n.attackerCanSuprise) + strconv.FormatBool(n.defenderCanSuprise)
+			if m, ok := merged[key]; ok {
+				m.p += n.p
+				m.attackerIpcLoss += n.attackerIpcLoss
+				m.defenderIpcLoss += n.defenderIpcLoss
+				m.aaaHits += n.aaaHits
+				m.kamikazeHits += n.kamikazeHits
+				continue
+			}
+			merged[key] = n
+			keys = append(keys, key)
+		}
+	}
+
+	next := make([]*exactBranch, 0, len(keys))
+	for _, key := range keys {
+		next = append(next, merged[key])
+	}
+
+	return next
+}
+
+// exactFate is a possible state of one side after taking casualties, with the
+// probability of ending up there and the expected loss weighted by it.
+type exactFate struct {
+	p         float64
+	formation map[string]int
+	key       string
+	loss      float64
+}
+
+// fates returns every state a formation may be left in when the hits of the
+// passed in distributions are assigned, in order, against the matching ool.
+func (e *exactSolver) fates(f map[string]int, dists [][]float64, ools [][]string) []*exactFate {
+	fates := []*exactFate{{p: 1, formation: f}}
+
+	for i, dist := range dists {
+		merged := map[string]*exactFate{}
+		var keys []string
+
+		for _, fate := range fates {
+			for hits, q := range dist {
+				if q == 0 {
+					continue
+				}
+
+				formation := fate.formation
+				var loss int
+				if hits > 0 {
+					formation = copyFormation(fate.formation)
+					loss = e.s.takeCasualties(formation, hits, ools[i])
+				}
+
+				p := fate.p * q
+				weightedLoss := fate.loss*q + p*float64(loss)
+
+				key := formationKey(formation)
+				if m, ok := merged[key]; ok {
+					m.p += p
+					m.loss += weightedLoss
+					continue
+				}
+				merged[key] = &exactFate{p: p, formation: formation, key: key, loss: weightedLoss}
+				keys = append(keys, key)
+			}
+		}
+
+		fates = make([]*exactFate, 0, len(keys))
+		for _, key := range keys {
+			fates = append(fates, merged[key])
+		}
+	}
+
+	return fates
+}
+
+// combineFates pairs every fate of the attackers with every fate of the
+// defenders, since the casualties of each side are independent of each other.
+func combineFates(attackers, defenders []*exactFate) []*exactBranch {
+	branches := make([]*exactBranch, 0, len(attackers)*len(defenders))
+	for _, a := range attackers {
+		for _, d := range defenders {
+			branches = append(branches, &exactBranch{
+				p:               a.p * d.p,
+				attackers:       a.formation,
+				defenders:       d.formation,
+				attackerIpcLoss: a.loss * d.p,
+				defenderIpcLoss: d.loss * a.p,
+				bombard:         []float64{1},
+				attackersKey:    a.key,
+				defendersKey:    d.key,
+			})
+		}
+	}
+
+	return branches
+}
+
+// rollMapTrials converts a RollMap into the dice it would roll.
+func (e *exactSolver) rollMapTrials(rollMap RollMap) (trials []exactTrial) {
+	for _, m := range rollMap {
+		// Just like calculateHits, a hitValue of 0 is never rolled.
+		if m.hitValue == 0 || m.num <= 0 {
+			continue
+		}
+
+		trials = append(trials, exactTrial{e.hitChance(m.hitValue), m.num})
+	}
+
+	return trials
+}
+
+// unitSliceTrials returns the dice rolled by rollForUnitSlice for the same
+// formation, slice and mode.
+func (e *exactSolver) unitSliceTrials(f map[string]int, slice []string, mode string) (trials []exactTrial) {
+	s := e.s
+	for _, alias := range slice {
+		if !hasUnit(f, alias) {
+			continue
+		}
+
+		unit := s.units.Find(realAlias(alias))
+		numUnits := numAllUnitsInFormation(f, unit.Alias)
+
+		if mode != "attack" {
+			rm := s.createRollMap(map[string]int{unit.Alias: numUnits}, mode)
+			trials = append(trials, e.rollMapTrials(rm)...)
+			continue
+		}
+
+		// Multi roll units score a single hit if any of their dice hit.
+		if unit.MultiRoll > 0 {
+			miss := 1.0
+			for _, t := range e.rollMapTrials(s.createRollMap(map[string]int{unit.Alias: unit.MultiRoll}, mode)) {
+				miss *= math.Pow(1-t.p, float64(t.n))
+			}
+			trials = append(trials, exactTrial{1 - miss, numUnits})
+			continue
+		}
+
+		var unitsAtPlusOne int
+		if unit.PlusOneRolls != nil {
+			unitsAtPlusOne = unit.PlusOneRolls(f)
+			numUnits = numUnits - unitsAtPlusOne
+		}
+		if unitsAtPlusOne > 0 && unit.Attack+1 > 0 {
+			trials = append(trials, exactTrial{e.hitChance(unit.Attack + 1), unitsAtPlusOne})
+		}
+		if numUnits > 0 && unit.Attack > 0 {
+			trials = append(trials, exactTrial{e.hitChance(unit.Attack), numUnits})
+		}
+	}
+
+	return trials
+}
+
+// hitChance is the probability of a single die scoring a hit at hitValue
+func (e *exactSolver) hitChance(hitValue int) float64 {
+	return math.Min(float64(hitValue), e.sides) / e.sides
+}
+
+// distribution returns the probability of every number of hits the dice may
+// score, indexed by the number of hits.
+func (e *exactSolver) distribution(trials []exactTrial) []float64 {
+	dist := []float64{1}
+	for _, t := range trials {
+		dist = convolve(dist, binomial(t.n, t.p))
+	}
+
+	return dist
+}
+
+// binomial returns the distribution of hits of n dice each hitting with
+// probability p
+func binomial(n int, p float64) []float64 {
+	dist := make([]float64, n+1)
+	for k := 0; k <= n; k++ {
+		dist[k] = choose(n, k) * math.Pow(p, float64(k)) * math.Pow(1-p, float64(n-k))
+	}
+
+	return dist
+}
+
+// choose is the binomial coefficient n over k
+func choose(n, k int) float64 {
+	c := 1.0
+	for i := 1; i <= k; i++ {
+		c = c * float64(n-k+i) / float64(i)
+	}
+
+	return c
+}
+
+// convolve returns the distribution of the sum of two independent hit
+// distributions
+func convolve(a, b []float64) []float64 {
+	dist := make([]float64, len(a)+len(b)-1)
+	for i, p := range a {
+		for j, q := range b {
+			dist[i+j] += p * q
+		}
+	}
+
+	return dist
+}
+
+// resolvedOutcome is the outcome of a conflict that is over, following the
+// outcome rules of resolveConflict.
+func (e *exactSolver) resolvedOutcome(a, d map[string]int) *exactOutcome {
+	o := &exactOutcome{}
+	if len(a) > 0 && len(d) > 0 {
+		o.draw = 1
+	} else if len(a) == 0 && len(d) == 0 {
+		o.draw = 1
+	} else if len(a) > 0 {
+		o.attackerWin = 1
+		if e.s.hasGroundUnits(a) {
+			o.territoryCaptured = 1
+		}
+	} else {
+		o.defenderWin = 1
+	}
+
+	return o
+}
+
+// stateKey identifies a battle state within the solver.
+func stateKey(a, d map[string]int, firstRound bool) string {
+	return formationKey(a) + "|" + formationKey(d) + "|" + strconv.FormatBool(firstRound)
+}
+
+// formationKey returns a string identifying a formation, independent of map
+// ordering.
+func formationKey(f map[string]int) string {
+	keys := make([]string, 0, len(f))
+	for alias, n := range f {
+		keys = append(keys, alias+":"+strconv.Itoa(n))
+	}
+	sort.Strings(keys)
+
+	return strings.Join(keys, ",")
+}
+
+// distributionKey returns a string identifying a distribution
+func distributionKey(dist []float64) string {
+	if len(dist) == 1 {
+		return ""
+	}
+
+	parts := make([]string, len(dist))
+	for i, p := range dist {
+		parts[i] = strconv.FormatFloat(p, 'g', -1, 64)
+	}
+
+	return "|" + strings.Join(parts, ",")
+}
+
+// copyFormation returns a copy of a formation that can be modified without
+// touching the original.
+func copyFormation(f map[string]int) map[string]int {
+	c := make(map[string]int, len(f))
+	for k, v := range f {
+		c[k] = v
+	}
+
+	return c
+}
@@ -0,0 +1,120 @@
+package oddsengine
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// TestExactSummaryOneOnOne checks the solver against odds calculated by hand.
+// One infantry attacking one infantry hits 1 in 6, and is hit 2 in 6.
+func TestExactSummaryOneOnOne(t *testing.T) {
+	s := NewSimulator()
+	summary, err := s.GetExactSummary(map[string]int{"inf": 1}, map[string]int{"inf": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Summary{
+		Exact:                       true,
+		AverageRounds:               2.25,
+		AttackerWinPercentage:       25,
+		DefenderWinPercentage:       62.5,
+		DrawPercentage:              12.5,
+		TerritoryCapturedPercentage: 25,
+		AttackerAvgIpcLoss:          2.25,
+		DefenderAvgIpcLoss:          1.13,
+		AvgIpcSwing:                 -1.13,
+		Confidence: Confidence{
+			AverageRounds:               Interval{Lower: 2.25, Upper: 2.25},
+			AttackerWinPercentage:       Interval{Lower: 25, Upper: 25},
+			DefenderWinPercentage:       Interval{Lower: 62.5, Upper: 62.5},
+			DrawPercentage:              Interval{Lower: 12.5, Upper: 12.5},
+			TerritoryCapturedPercentage: Interval{Lower: 25, Upper: 25},
+			AttackerAvgIpcLoss:          Interval{Lower: 2.25, Upper: 2.25},
+			DefenderAvgIpcLoss:          Interval{Lower: 1.13, Upper: 1.13},
+			AvgIpcSwing:                 Interval{Lower: -1.13, Upper: -1.13},
+		},
+	}
+	if !reflect.DeepEqual(*summary, expected) {
+		t.Errorf("exact summary was not calculated correctly\nexpected: %+v\nactual: %+v", expected, *summary)
+	}
+
+	// The attacker captures the territory in a quarter of the conflicts
+	summary, err = NewSimulator(WithTerritoryValue(4)).GetExactSummary(map[string]int{"inf": 1}, map[string]int{"inf": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(summary.AvgIpcSwing+0.125) > 0.01 {
+		t.Errorf("territory value was not counted in the IPC swing\n%+v", *summary)
+	}
+}
+
+// TestExactSummaryMatchesSimulation compares the exact odds against a large
+// seeded simulation of the same conflict.
+func TestExactSummaryMatchesSimulation(t *testing.T) {
+	values := []struct {
+		game      string
+		attackers map[string]int
+		defenders map[string]int
+	}{
+		{"1940", map[string]int{"inf": 4, "art": 2, "tan": 2}, map[string]int{"inf": 5, "tan": 1}},
+		{"1940", map[string]int{"inf": 3, "fig": 2, "bat": 1}, map[string]int{"aaa": 1, "inf": 3, "art": 1}},
+		{"1940", map[string]int{"sub": 2, "des": 1, "cru": 1, "fig": 1}, map[string]int{"sub": 2, "bat": 1}},
+		{"1940", map[string]int{"cru": 2, "car": 1, "fig": 2}, map[string]int{"kam": 1, "des": 2, "sub": 1}},
+		{"1942", map[string]int{"inf": 3, "tan": 2, "bom": 1}, map[string]int{"inf": 4, "aaa": 1}},
+		{"deluxe", map[string]int{"inf": 3, "lar": 1, "htk": 1}, map[string]int{"inf": 3, "hif": 1}},
+	}
+
+	for _, tt := range values {
+		exact, err := NewSimulator(WithGame(tt.game)).GetExactSummary(tt.attackers, tt.defenders)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		simulated, err := NewSimulator(WithGame(tt.game), WithIterations(20000), WithSeed(1)).GetSummary(tt.attackers, tt.defenders)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if math.Abs(exact.AttackerWinPercentage-simulated.AttackerWinPercentage) > 1.5 ||
+			math.Abs(exact.DefenderWinPercentage-simulated.DefenderWinPercentage) > 1.5 ||
+			math.Abs(exact.TerritoryCapturedPercentage-simulated.TerritoryCapturedPercentage) > 1.5 ||
+			math.Abs(exact.AverageRounds-simulated.AverageRounds) > 0.1 ||
+			math.Abs(exact.AttackerAvgIpcLoss-simulated.AttackerAvgIpcLoss) > 0.5 ||
+			math.Abs(exact.DefenderAvgIpcLoss-simulated.DefenderAvgIpcLoss) > 0.5 ||
+			math.Abs(exact.AvgIpcSwing-simulated.AvgIpcSwing) > 0.7 ||
+			math.Abs(exact.AAAHitsAverage-simulated.AAAHitsAverage) > 0.05 ||
+			math.Abs(exact.KamikazeHitsAverage-simulated.KamikazeHitsAverage) > 0.05 {
+			t.Errorf("exact summary strays from the simulation\nattackers: %v\ndefenders: %v\nexact: %+v\nsimulated: %+v", tt.attackers, tt.defenders, *exact, *simulated)
+		}
+
+		win := simulated.Confidence.AttackerWinPercentage
+		if exact.AttackerWinPercentage < win.Lower || exact.AttackerWinPercentage > win.Upper {
+			t.Errorf("exact win percentage %v is outside the simulated confidence interval %+v", exact.AttackerWinPercentage, win)
+		}
+	}
+}
+
+func TestBinomial(t *testing.T) {
+	values := []struct {
+		n        int
+		p        float64
+		expected []float64
+	}{
+		{0, 0.5, []float64{1}},
+		{1, 1.0 / 6, []float64{5.0 / 6, 1.0 / 6}},
+		{2, 0.5, []float64{0.25, 0.5, 0.25}},
+		{3, 1, []float64{0, 0, 0, 1}},
+	}
+
+	for _, tt := range values {
+		actual := binomial(tt.n, tt.p)
+		for k := range tt.expected {
+			if math.Abs(actual[k]-tt.expected[k]) > 1e-12 {
+				t.Errorf("binomial distribution was not calculated correctly\nexpected: %v\nactual: %v", tt.expected, actual)
+				break
+			}
+		}
+	}
+}
@@ -1,12 +1,14 @@
 package oddsengine
 
 type FirstRoundResult struct {
-	AttackerHits int `json:"attackerHits"`
-	DefenderHits int `json:"defenderHits"`
-	Frequency    int `json:"frequency"`
-	AttackerWin  int `json:"attackerWin"`
-	DefenderWin  int `json:"defenderWin"`
-	Draw         int `json:"draw"`
+	AttackerHits    int `json:"attackerHits"`
+	DefenderHits    int `json:"defenderHits"`
+	Frequency       int `json:"frequency"`
+	AttackerWin     int `json:"attackerWin"`
+	DefenderWin     int `json:"defenderWin"`
+	Draw            int `json:"draw"`
+	AttackerRetreat int `json:"attackerRetreat"`
+	Unresolved      int `json:"unresolved"`
 }
 
 type FirstRoundResultCollection []FirstRoundResult
@@ -20,6 +22,8 @@ func (fc FirstRoundResultCollection) Add(result FirstRoundResult) FirstRoundResu
 			fc[i].AttackerWin += result.AttackerWin
 			fc[i].DefenderWin += result.DefenderWin
 			fc[i].Draw += result.Draw
+			fc[i].AttackerRetreat += result.AttackerRetreat
+			fc[i].Unresolved += result.Unresolved
 			added = true
 			break
 		}
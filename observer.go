@@ -0,0 +1,266 @@
+package oddsengine
+
+// Observer is notified of every step of the conflicts a Simulator resolves,
+// to gather statistics or animate a battle without changing how it is fought.
+// Each conflict is observed in order on a single goroutine, but a Simulator
+// resolves many conflicts at once, so an Observer must be safe for concurrent
+// use. An Observer relying on seeing one conflict at a time should be used with
+// WithWorkers(1).
+//
+// The formations of an event are those the engine is fighting with, they must
+// not be modified, nor kept once the call returns. Submerged units, and
+// attackers withdrawn from an amphibious assault, are no longer part of them.
+type Observer interface {
+	// BattleStarted is called before the special attacks of a battle, with
+	// the units it starts with
+	BattleStarted(e BattleEvent)
+
+	// RoundStarted is called before the rolls of each round
+	RoundStarted(e BattleEvent)
+
+	// PhaseRolled is called once a side has rolled a phase, with the dice and
+	// hits of the phase
+	PhaseRolled(e BattleEvent)
+
+	// HitsAssigned is called before a side takes casualties for hits, with
+	// the number of hits and the units they may be assigned to
+	HitsAssigned(e BattleEvent)
+
+	// CasualtiesRemoved is called once a side has taken casualties, with the
+	// units lost and damaged
+	CasualtiesRemoved(e BattleEvent)
+
+	// BattleEnded is called once a battle is over, with its outcome and the
+	// units left
+	BattleEnded(e BattleEvent)
+}
+
+// BattleEvent is a step of a battle passed to an Observer. The fields that do
+// not concern the step are left empty.
+type BattleEvent struct {
+	// Battle The battle of the conflict, starting at 1. An amphibious assault
+	// fighting a sea battle first is made of two battles.
+	Battle int
+
+	// Round The round of the battle, starting at 1. The special attacks made
+	// before the first round are part of it. 0 before the first round starts.
+	Round int
+
+	// Attackers The attacking units still fighting
+	Attackers map[string]int
+
+	// Defenders The defending units still fighting
+	Defenders map[string]int
+
+	// Phase The phase rolled or casualties taken, as recorded in a Trace. The
+	// faces of the dice are only recorded in a Trace. Set for PhaseRolled and
+	// CasualtiesRemoved.
+	Phase *TracePhase
+
+	// Attacking Whether the attacker takes the casualties. Set for
+	// HitsAssigned.
+	Attacking bool
+
+	// Hits The number of hits to take casualties for. Set for HitsAssigned.
+	Hits int
+
+	// Restriction The units the hits may be assigned to. Set for
+	// HitsAssigned.
+	Restriction HitRestriction
+
+	// Outcome The outcome of the battle, as in a ConflictProfile. Set for
+	// BattleEnded.
+	Outcome int
+}
+
+// NopObserver ignores every event. It can be embedded in an Observer only
+// interested in some of them.
+type NopObserver struct{}
+
+// BattleStarted implementing Observer
+func (NopObserver) BattleStarted(e BattleEvent) {}
+
+// RoundStarted implementing Observer
+func (NopObserver) RoundStarted(e BattleEvent) {}
+
+// PhaseRolled implementing Observer
+func (NopObserver) PhaseRolled(e BattleEvent) {}
+
+// HitsAssigned implementing Observer
+func (NopObserver) HitsAssigned(e BattleEvent) {}
+
+// CasualtiesRemoved implementing Observer
+func (NopObserver) CasualtiesRemoved(e BattleEvent) {}
+
+// BattleEnded implementing Observer
+func (NopObserver) BattleEnded(e BattleEvent) {}
+
+// observation notifies an Observer of the steps of a conflict as the engine
+// takes them
+type observation struct {
+	observer Observer
+
+	// battle and round are those being fought
+	battle, round int
+
+	// attackers and defenders are the units of the battle being fought
+	attackers, defenders map[string]int
+
+	// phase is the phase being rolled, nil when there is none
+	phase *TracePhase
+}
+
+// The methods below notify the observer, doing nothing when the observation
+// is nil so that the engine may call them whether or not it is observed.
+
+// event creates a BattleEvent of the battle being fought
+func (o *observation) event() BattleEvent {
+	return BattleEvent{
+		Battle:    o.battle,
+		Round:     o.round,
+		Attackers: o.attackers,
+		Defenders: o.defenders,
+	}
+}
+
+// startBattle notifies the start of a battle between the attackers and
+// defenders
+func (o *observation) startBattle(attackers, defenders map[string]int) {
+	if o == nil {
+		return
+	}
+
+	o.battle++
+	o.round = 0
+	o.attackers, o.defenders = attackers, defenders
+	o.observer.BattleStarted(o.event())
+}
+
+// endBattle notifies the outcome of the battle
+func (o *observation) endBattle(outcome int) {
+	if o == nil {
+		return
+	}
+
+	e := o.event()
+	e.Outcome = outcome
+	o.observer.BattleEnded(e)
+}
+
+// startRound notifies the start of a round of the battle
+func (o *observation) startRound() {
+	if o == nil {
+		return
+	}
+
+	o.round++
+	o.observer.RoundStarted(o.event())
+}
+
+// startPhase starts gathering the rolls of a phase
+func (o *observation) startPhase(phase string, attacking bool) {
+	if o == nil {
+		return
+	}
+
+	o.phase = &TracePhase{Phase: phase, Side: traceSide(attacking)}
+}
+
+// roll gathers a group of dice rolled in the phase
+func (o *observation) roll(dice, hitValue int, hits int) {
+	if o == nil || o.phase == nil {
+		return
+	}
+
+	o.phase.Rolls = append(o.phase.Rolls, TraceRoll{HitValue: hitValue, Dice: dice, Hits: hits})
+}
+
+// endPhase notifies the hits scored in the phase. As in a Trace, a phase in
+// which no dice were rolled and no hits scored is left out.
+func (o *observation) endPhase(hits int) {
+	if o == nil || o.phase == nil {
+		return
+	}
+
+	p := o.phase
+	o.phase = nil
+	if len(p.Rolls) == 0 && hits == 0 {
+		return
+	}
+
+	p.Hits = hits
+	e := o.event()
+	e.Phase = p
+	o.observer.PhaseRolled(e)
+}
+
+// assign notifies the hits a side is about to take casualties for
+func (o *observation) assign(attacking bool, hits int, restriction HitRestriction) {
+	if o == nil {
+		return
+	}
+
+	e := o.event()
+	e.Attacking = attacking
+	e.Hits = hits
+	e.Restriction = restriction
+	o.observer.HitsAssigned(e)
+}
+
+// casualties notifies the casualties a side took for the hits
+func (o *observation) casualties(attacking bool, hits int, before, after map[string]int) {
+	if o == nil {
+		return
+	}
+
+	p := casualtyPhase(attacking, hits, before, after)
+	e := o.event()
+	e.Phase = &p
+	o.observer.CasualtiesRemoved(e)
+}
+
+// The engine records each step of a conflict through the methods below, onto
+// its trace and observation, whichever of them are set.
+
+// recordBattle records the start of a battle between the attackers and
+// defenders
+func (s *Simulator) recordBattle(attackers, defenders map[string]int) {
+	s.trace.battle(attackers, defenders)
+	s.observing.startBattle(attackers, defenders)
+}
+
+// recordEndBattle records the outcome of the battle
+func (s *Simulator) recordEndBattle(outcome int) {
+	s.trace.endBattle(outcome)
+	s.observing.endBattle(outcome)
+}
+
+// recordRound records the start of a round
+func (s *Simulator) recordRound() {
+	s.trace.round()
+	s.observing.startRound()
+}
+
+// recordPhase records the start of a phase rolled by a side
+func (s *Simulator) recordPhase(phase string, attacking bool) {
+	s.trace.phase(phase, attacking)
+	s.observing.startPhase(phase, attacking)
+}
+
+// recordRoll records a group of dice rolled, the faces only being known when
+// tracing
+func (s *Simulator) recordRoll(dice, hitValue int, faces []int, hits int) {
+	s.trace.roll(dice, hitValue, faces, hits)
+	s.observing.roll(dice, hitValue, hits)
+}
+
+// recordHits records the hits scored in the phase
+func (s *Simulator) recordHits(hits int) {
+	s.trace.hits(hits)
+	s.observing.endPhase(hits)
+}
+
+// recording returns whether the steps of the conflict are being recorded
+func (s *Simulator) recording() bool {
+	return s.trace != nil || s.observing != nil
+}
@@ -0,0 +1,119 @@
+package oddsengine
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// recordingObserver records every event it is notified of as a line of text
+type recordingObserver struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (o *recordingObserver) record(format string, a ...interface{}) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.events = append(o.events, fmt.Sprintf(format, a...))
+}
+
+func (o *recordingObserver) BattleStarted(e BattleEvent) {
+	o.record("battle %v started %v %v", e.Battle, e.Attackers, e.Defenders)
+}
+
+func (o *recordingObserver) RoundStarted(e BattleEvent) {
+	o.record("round %v started", e.Round)
+}
+
+func (o *recordingObserver) PhaseRolled(e BattleEvent) {
+	o.record("%v %v rolled %v hits", e.Phase.Side, e.Phase.Phase, e.Phase.Hits)
+}
+
+func (o *recordingObserver) HitsAssigned(e BattleEvent) {
+	o.record("%v hits assigned to the %v", e.Hits, traceSide(e.Attacking))
+}
+
+func (o *recordingObserver) CasualtiesRemoved(e BattleEvent) {
+	o.record("%v lost %v %v %v", e.Phase.Side, e.Phase.Lost, e.Attackers, e.Defenders)
+}
+
+func (o *recordingObserver) BattleEnded(e BattleEvent) {
+	o.record("battle %v ended %v", e.Battle, e.Outcome)
+}
+
+// battleCounter counts the battles ended and the dice faces seen, ignoring
+// every other event
+type battleCounter struct {
+	NopObserver
+	mu    sync.Mutex
+	ended int
+	faces int
+}
+
+func (o *battleCounter) PhaseRolled(e BattleEvent) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, roll := range e.Phase.Rolls {
+		o.faces += len(roll.Faces)
+	}
+}
+
+func (o *battleCounter) BattleEnded(e BattleEvent) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.ended++
+}
+
+func TestObserver(t *testing.T) {
+	// Every die hits, the attacker is destroyed in the first round
+	o := &recordingObserver{}
+	s := NewSimulator(WithDice(&countingDice{}), WithObserver(o), WithWorkers(1), WithIterations(1))
+	if _, err := s.GetSummary(map[string]int{"inf": 2}, map[string]int{"inf": 3, "art": 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"battle 1 started map[inf:2] map[art:1 inf:3]",
+		"round 1 started",
+		"attacker main rolled 2 hits",
+		"defender main rolled 4 hits",
+		"2 hits assigned to the defender",
+		"defender lost map[inf:2] map[inf:2] map[art:1 inf:1]",
+		"4 hits assigned to the attacker",
+		"attacker lost map[inf:2] map[] map[art:1 inf:1]",
+		"battle 1 ended -1",
+	}
+	if !reflect.DeepEqual(expected, o.events) {
+		t.Errorf("events were not observed correctly\nexpected: %q\nactual: %q", expected, o.events)
+	}
+}
+
+func TestObserverSummary(t *testing.T) {
+	attackers := map[string]int{"inf": 3, "tan": 1}
+	defenders := map[string]int{"inf": 3}
+
+	plain, err := NewSimulator(WithIterations(500), WithSeed(7)).GetSummary(attackers, defenders)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Observing leaves the summary unchanged, and sees every conflict
+	o := &battleCounter{}
+	observed, err := NewSimulator(WithIterations(500), WithSeed(7), WithObserver(o)).GetSummary(attackers, defenders)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(plain, observed) {
+		t.Errorf("observing changed the summary\nexpected: %+v\nactual: %+v", plain, observed)
+	}
+	if o.ended != 500 {
+		t.Errorf("expected 500 battles to end, observed %v", o.ended)
+	}
+
+	// The faces of the dice are only recorded when tracing
+	if o.faces != 0 {
+		t.Errorf("observing recorded %v dice faces", o.faces)
+	}
+}
@@ -1,101 +1,213 @@
 package oddsengine
 
 import (
+	"context"
 	"fmt"
-	"math/rand"
+	"math"
 	"sort"
 	"strings"
-	"time"
 )
 
-var (
-	// mustTakeTerritory is a flag that will reserve the highest value land unit,
-	// to allow the attacker to take the territory
-	mustTakeTerritory bool
+// defaultSimulator is the Simulator backing the package level functions.
+var defaultSimulator = NewSimulator()
 
-	// iterations is the number of times we will run the sim and generate a
-	// ConflictProfile for the Summary. Default is 1000
-	iterations = 1000
+// GetSummary is the function that ties everything together, Returns a summary
+// of the conflict. Runs against the default Simulator.
+func GetSummary(attackers, defenders map[string]int) (*Summary, error) {
+	return defaultSimulator.GetSummary(attackers, defenders)
+}
 
-	// activeGame is the game current being run by the simulator
-	activeGame = "1940"
+// GetSummaryContext returns a summary of the conflict, stopping early when ctx
+// is cancelled or its deadline passes. Runs against the default Simulator.
+func GetSummaryContext(ctx context.Context, attackers, defenders map[string]int) (*Summary, error) {
+	return defaultSimulator.GetSummaryContext(ctx, attackers, defenders)
+}
 
-	// activeUnits are the units available in the current game version
-	// default units are 1940 units.
-	activeUnits = getUnitsForGame(activeGame)
+// SetBaseOol allow a custom baseOol to be set for the conflict. Both sides
+// take their losses in the order of the custom baseOol.
+func SetBaseOol(ool []string) {
+	defaultSimulator.customOol = ool
+	defaultSimulator.setup()
+}
 
-	// oolProfile is the general strategy for taking losses. Possible values are
-	// "cost" and "hitValue"
-	oolProfile = "cost"
-)
+// SetIterations changes the number of times the simulation will be ran. A
+// number of 0 or less is rejected when the simulation is run.
+func SetIterations(i int) {
+	defaultSimulator.iterations = i
+}
+
+// SetMustTakeTerritory keeps an attacking land unit alive to take the
+// territory, the same as SetKeepLandUnits(1). Passing false keeps none.
+func SetMustTakeTerritory(a bool) {
+	defaultSimulator.keepLandUnits = mustTakeTerritory(a, defaultSimulator.keepLandUnits)
+}
+
+// SetKeepLandUnits sets the number of attacking land units kept alive for as
+// long as any other attacking unit is able to take the hits
+func SetKeepLandUnits(n int) {
+	defaultSimulator.keepLandUnits = n
+}
+
+// SetPrecision runs simulations until the win percentages are within margin
+// percentage points, or until maxIterations conflicts have been resolved.
+// Passing a margin of 0 goes back to running a fixed number of iterations.
+func SetPrecision(margin float64, maxIterations int) {
+	defaultSimulator.margin = margin
+	defaultSimulator.maxIterations = maxIterations
+}
+
+// SetLowLuck toggles the Low Luck dice mode for the simulation
+func SetLowLuck(a bool) {
+	defaultSimulator.lowLuck = a
+}
+
+// SetLowLuckSpecialRolls toggles whether AAA, kamikaze and multi roll units use
+// Low Luck as well, when the Low Luck dice mode is set
+func SetLowLuckSpecialRolls(a bool) {
+	defaultSimulator.lowLuckSpecial = a
+}
+
+// SetDice rolls every die of the simulations with the passed in Dice, passing
+// nil goes back to the simulator's own random source
+func SetDice(d Dice) {
+	defaultSimulator.dice = d
+}
+
+// SetWorkers sets the number of conflicts resolved in parallel, 0 uses one
+// worker per CPU
+func SetWorkers(n int) {
+	defaultSimulator.workers = n
+}
+
+// SetRetreat sets when the attacker retreats from the conflict
+func SetRetreat(r RetreatPolicy) {
+	defaultSimulator.retreat = r
+}
+
+// SetMaxRounds limits the number of rounds fought in a conflict, 0 fights
+// until the conflict is resolved
+func SetMaxRounds(n int) {
+	defaultSimulator.maxRounds = n
+}
+
+// SetSubmerge sets when the attacker's and defender's submarines submerge
+func SetSubmerge(attacker, defender SubmergePolicy) {
+	defaultSimulator.attackerSubmerge = attacker
+	defaultSimulator.defenderSubmerge = defender
+}
+
+// SetOolProfiles sets the strategy each side uses to take its losses
+func SetOolProfiles(attacker, defender OolProfile) {
+	defaultSimulator.attackerOolProfile = attacker
+	defaultSimulator.defenderOolProfile = defender
+	defaultSimulator.setup()
+}
+
+// SetCasualtySelectors sets how each side chooses its casualties
+func SetCasualtySelectors(attacker, defender CasualtySelector) {
+	defaultSimulator.attackerSelector = attacker
+	defaultSimulator.defenderSelector = defender
+}
+
+// SetTerritoryValue sets the IPC income of the territory being attacked
+func SetTerritoryValue(v int) {
+	defaultSimulator.territoryValue = v
+}
 
-// init the random seed for this run of the engine
-func init() {
-	rand.Seed(time.Now().UTC().UnixNano())
-	setupOol()
+// SetSampleTraces sets the number of conflicts of each simulation traced and
+// attached to the Summary
+func SetSampleTraces(n int) {
+	defaultSimulator.sampleTraces = n
+}
+
+// SetObserver notifies the Observer of every step of the conflicts resolved,
+// passing nil stops notifying
+func SetObserver(o Observer) {
+	defaultSimulator.observer = o
+}
+
+// SetCargoValue sets the IPC value of the cargo lost with every transport
+func SetCargoValue(v int) {
+	defaultSimulator.cargoValue = v
+}
+
+// SetSeed makes the simulations reproducible, the same seed, formations and
+// iterations will always produce the same summary.
+func SetSeed(seed int64) {
+	defaultSimulator.seed = seed
+	defaultSimulator.seeded = true
+}
+
+// SetGame sets the game up internally. Altering unit makeup, and ool
+func SetGame(g string) {
+	defaultSimulator.game = g
+	defaultSimulator.customOol = nil
+	defaultSimulator.setup()
 }
 
 // GetSummary is the function that ties everything together, Returns a summary
 // of the conflict.
-func GetSummary(attackers, defenders map[string]int) (*Summary, error) {
+func (s *Simulator) GetSummary(attackers, defenders map[string]int) (*Summary, error) {
+	return s.GetSummaryContext(context.Background(), attackers, defenders)
+}
+
+// GetSummaryContext returns a summary of the conflict, stopping early when ctx
+// is cancelled or its deadline passes. The summary of a stopped simulation
+// covers only the conflicts resolved so far, is flagged as Incomplete and is
+// returned along with the error of the context.
+func (s *Simulator) GetSummaryContext(ctx context.Context, attackers, defenders map[string]int) (*Summary, error) {
 	var err error
 
-	err = checkUnitValidity(attackers)
+	err = s.checkUnitValidity(attackers)
 	if err != nil {
 		return &Summary{}, err
 	}
 
-	err = checkUnitValidity(defenders)
+	err = s.checkUnitValidity(defenders)
 	if err != nil {
 		return &Summary{}, err
 	}
-	var profiles []ConflictProfile
 
-	if mustTakeTerritory {
-		reserveHighestValueLandUnit(attackers)
+	err = s.checkIterations()
+	if err != nil {
+		return &Summary{}, err
 	}
 
-	ool := customizeOol(attackers, defenders)
-	ch := make(chan ConflictProfile, iterations)
+	ool := s.customizeOol(attackers, defenders)
 
-	for i := 0; i < iterations; i++ {
-		go func() {
-			ch <- *resolveConflict(attackers, defenders, ool)
-		}()
-	}
+	acc, err := s.simulate(ctx, attackers, defenders, ool)
+	summary := acc.summary()
 
-	for i := 0; i < iterations; i++ {
-		profiles = append(profiles, <-ch)
+	if err != nil {
+		summary.Incomplete = true
+		return summary, err
 	}
-	return generateSummary(profiles), nil
-}
 
-// SetBaseOol allow a custom baseOol to be set for the conflict.
-func SetBaseOol(ool []string) {
-	baseOol = ool
+	return summary, nil
 }
 
-// SetIterations changes the number of times the simulation will be ran.
-func SetIterations(i int) {
-	iterations = i
-}
+// resolveConflict is the big boy here. When given a map of attacking and
+// defending units, will resolve the conflict into a profile representing a
+// whole host of data about the conflict.
+func (s *Simulator) resolveConflict(a, d map[string]int, ool *conflictOol) *ConflictProfile {
+	// The observer is notified of the steps of each conflict in turn
+	if s.observer != nil && s.observing == nil {
+		w := *s
+		w.observing = &observation{observer: s.observer}
+		return w.resolveConflict(a, d, ool)
+	}
 
-// SetMustTakeTerritory toggles the mustTakeTerritory flag for the simulation
-func SetMustTakeTerritory(a bool) {
-	mustTakeTerritory = a
-}
+	if s.assault != nil {
+		return s.resolveAmphibiousAssault(a, d, ool)
+	}
 
-// SetGame sets the game up internally. Altering unit makeup, and ool
-func SetGame(g string) {
-	activeGame = g
-	activeUnits = getUnitsForGame(g)
-	setupOol()
+	return s.fight(a, d, ool, nil)
 }
 
-// resolveConflict is the big boy here. When given a map of attacking and
-// defending units, will resolve the conflict into a profile representing a
-// whole host of data about the conflict.
-func resolveConflict(a, d map[string]int, ool []string) *ConflictProfile {
+// fight resolves the conflict between the attackers and defenders round by
+// round. The landing is the seaborne part of an amphibious assault, nil when
+// the conflict is not one.
+func (s *Simulator) fight(a, d map[string]int, ool *conflictOol, landing *landing) *ConflictProfile {
 	// We need to copy the passed in attackers and defenders so as to not
 	// destroy the orininal map.
 	attackers := make(map[string]int, len(a))
@@ -111,26 +223,88 @@ func resolveConflict(a, d map[string]int, ool []string) *ConflictProfile {
 	}
 
 	profile := new(ConflictProfile)
+	s.recordBattle(attackers, defenders)
+
+	// Submerged units are kept aside, they are neither lost nor remaining
+	attackerSubmerged := map[string]int{}
+	defenderSubmerged := map[string]int{}
+
+	// Bombarding ships are kept aside too, they are never hit
+	bombarding := map[string]int{}
+
+	// Attackers withdrawn from an amphibious assault are kept aside too, the
+	// seaborne units fighting on without them
+	withdrawn := map[string]int{}
+	var retreatable map[string]int
+	if landing != nil {
+		retreatable = copyFormation(landing.retreatable)
+	}
 
 	// Let's loop infinitely here because we don't know how many rounds the
 	// conflict will lets. And technically, the conflict CAN go on infinitely.
 	for {
 
 		// If the battle is resolved we can exit here
-		if isResolved(attackers, defenders) {
+		if s.isResolved(attackers, defenders) {
 			break
 		}
 
-		// Check if the defender has units capable of defending. If so we
-		// calculate those casualties.
-		if conflictIsAutoKill(defenders, attackers, len(profile.DefenderHits) == 0) {
-			profile.DefenderIpcLoss += takeCasualties(defenders, getTotalNumUnits(defenders), ool)
+		// The attacker may decide to retreat at the end of a round
+		if s.shouldRetreat(attackers, defenders, len(profile.DefenderHits)) {
+			// Seaborne units are unable to retreat, only the rest of the
+			// attackers withdraw. The attacker retreats when none are left.
+			if retreatable != nil {
+				withdraw(attackers, retreatable, withdrawn)
+				retreatable = map[string]int{}
+			}
+			if len(attackers) == 0 || landing == nil {
+				profile.Outcome = AttackerRetreat
+				break
+			}
+		}
+
+		// Stop once the maximum number of rounds have been fought, leaving
+		// the conflict unresolved
+		if s.maxRounds > 0 && len(profile.DefenderHits) >= s.maxRounds {
+			profile.Outcome = Unresolved
+			break
+		}
+
+		// Submarines following a submerge policy may leave the conflict
+		// before the round is fought. The conflict is checked again, as it
+		// may now be over.
+		round := len(profile.DefenderHits) + 1
+		attackersSubmerged := s.attackerSubmerge.shouldSubmerge(round, profile.AttackerIpcLoss, profile.DefenderIpcLoss) &&
+			s.submerge(attackers, defenders, attackerSubmerged)
+		defendersSubmerged := s.defenderSubmerge.shouldSubmerge(round, profile.DefenderIpcLoss, profile.AttackerIpcLoss) &&
+			s.submerge(defenders, attackers, defenderSubmerged)
+		if attackersSubmerged || defendersSubmerged {
+			continue
+		}
+
+		s.recordRound()
+
+		// Defenseless transports left on their own are destroyed by any enemy
+		// able to fire at them.
+		if s.hasOnlyDefenselessTransports(attackers) && !s.conflictIsAutoKill(defenders, attackers, len(profile.DefenderHits) == 0) {
+			profile.AttackerIpcLoss += s.selectCasualties(true, attackers, defenders, getTotalNumUnits(attackers), ool.attacker, AnyUnit)
+			break
+		}
+
+		// Check if the defender has units capable of defending. If not, the
+		// attackers able to fire take them all. Neither side is able to hit
+		// the other otherwise, which is a draw.
+		if s.conflictIsAutoKill(defenders, attackers, len(profile.DefenderHits) == 0) {
+			if !s.canScoreHits(attackers) {
+				break
+			}
+			profile.DefenderIpcLoss += s.selectCasualties(false, defenders, attackers, getTotalNumUnits(defenders), ool.defender, AnyUnit)
 			break
 		}
 
 		// If the battle is resolved we can exit here Yes we are checking again.
 		// because the auto kill casualties may resolve the battle for us.
-		if isResolved(attackers, defenders) {
+		if s.isResolved(attackers, defenders) {
 			break
 		}
 		// We need to calculate the TOTAL number of hits by attacker and defender
@@ -159,11 +333,13 @@ func resolveConflict(a, d map[string]int, ool []string) *ConflictProfile {
 			// ships MAX. To be completely accurate reallly, we need to accept
 			// some form of input regarding which ships the kamikaze were
 			// assigned to, however that isn't within the scope ATM.
-			kamikazeHits := rollForUnitSlice(defenders, []string{"kam"}, "defend")
+			s.recordPhase(PhaseKamikaze, false)
+			kamikazeHits := s.calculateSpecialHits(s.createRollMap(map[string]int{"kam": numAllUnitsInFormation(defenders, "kam")}, "defend"))
 			profile.KamikazeHits = kamikazeHits
+			s.recordHits(kamikazeHits)
 
 			if kamikazeHits > 0 {
-				profile.AttackerIpcLoss += takeCasualties(attackers, kamikazeHits, surfaceShips)
+				profile.AttackerIpcLoss += s.selectCasualties(true, attackers, defenders, kamikazeHits, s.surfaceShips, SurfaceShipsOnly)
 			}
 
 			// kamikaze are a one time use so delete them here.
@@ -175,23 +351,42 @@ func resolveConflict(a, d map[string]int, ool []string) *ConflictProfile {
 			// If we have AAA ability in the zone, we need to calculate those hits
 			// first, and resolve the casualties before the defender is able to
 			// fire back.
-			AAARollMap := getAAARollMap(attackers, defenders)
-			AAAHits := calculateHits(AAARollMap)
+			s.recordPhase(PhaseAAA, false)
+			AAARollMap := s.getAAARollMap(attackers, defenders)
+			AAAHits := s.calculateSpecialHits(AAARollMap)
 			profile.AAAHits = AAAHits
+			s.recordHits(AAAHits)
 
 			if AAAHits > 0 {
-				profile.AttackerIpcLoss += takeCasualties(attackers, AAAHits, aircraft)
+				profile.AttackerIpcLoss += s.selectCasualties(true, attackers, defenders, AAAHits, s.aircraft, AircraftOnly)
 			}
 
 			// Ships that are capable of bombardment must go in this phase. They
 			// do not prevent the hit defenders from attacking back, so we do
 			// not take casualties.
-			if canBombard(attackers) {
-				attackingHits += rollForUnitSlice(attackers, bombardShips, "attack")
+			// The ships supporting an amphibious assault were picked before
+			// the conflict, one per seaborne unit.
+			if landing != nil {
+				s.recordPhase(PhaseBombard, true)
+				attackingHits += s.rollForUnitSlice(landing.bombard, s.bombardShips, "attack")
+				s.recordHits(attackingHits)
+
+				for alias, n := range landing.bombard {
+					bombarding[alias] += n
+				}
+			} else if s.canBombard(attackers) {
+				s.recordPhase(PhaseBombard, true)
+				attackingHits += s.rollForUnitSlice(attackers, s.bombardShips, "attack")
+				s.recordHits(attackingHits)
 
 				// We need to remove the bombardships from the formation right
 				// away to prevent them from getting hits assigned.
-				for _, ship := range bombardShips {
+				for _, ship := range s.bombardShips {
+					for _, key := range []string{ship, "+" + ship, "-" + ship, "*" + ship} {
+						if n := attackers[key]; n > 0 {
+							bombarding[key] += n
+						}
+					}
 					deleteUnitFromFormation(attackers, ship)
 				}
 			}
@@ -210,8 +405,8 @@ func resolveConflict(a, d map[string]int, ool []string) *ConflictProfile {
 		var defenderSupriseHits int
 
 		// Calculate Submarine Suprise attacks
-		attackerCanSuprise := canSupriseAttack(attackers, defenders)
-		defenderCanSuprise := canSupriseAttack(defenders, attackers)
+		attackerCanSuprise := s.canSupriseAttack(attackers, defenders)
+		defenderCanSuprise := s.canSupriseAttack(defenders, attackers)
 
 		// Defender and Attacker suprise attacks need to be calculated at the
 		// same time. We aren't able to take casualties immediatly after,
@@ -219,21 +414,25 @@ func resolveConflict(a, d map[string]int, ool []string) *ConflictProfile {
 		// don't want the attacking hit to destroy the sub, not allowing it to
 		// get it's shot.
 		if attackerCanSuprise {
-			attackerSupriseHits = rollSubs(attackers, "attack")
+			s.recordPhase(PhaseSurpriseAttack, true)
+			attackerSupriseHits = s.rollSubs(attackers, "attack")
+			s.recordHits(attackerSupriseHits)
 		}
 		if defenderCanSuprise {
-			defenderSupriseHits = rollSubs(defenders, "defend")
+			s.recordPhase(PhaseSurpriseAttack, false)
+			defenderSupriseHits = s.rollSubs(defenders, "defend")
+			s.recordHits(defenderSupriseHits)
 		}
 
 		// After the hits are calculated, we may take the casualties.
-		profile.DefenderIpcLoss += takeCasualties(defenders, attackerSupriseHits, ships)
-		profile.AttackerIpcLoss += takeCasualties(attackers, defenderSupriseHits, ships)
+		profile.DefenderIpcLoss += s.selectCasualties(false, defenders, attackers, attackerSupriseHits, s.ships, ShipsOnly)
+		profile.AttackerIpcLoss += s.selectCasualties(true, attackers, defenders, defenderSupriseHits, s.ships, ShipsOnly)
 
 		/**
 		 * Generate standard combat roll map
 		 */
-		attackerRollMap := createRollMap(attackers, "attack")
-		defenderRollMap := createRollMap(defenders, "defend")
+		attackerRollMap := s.createRollMap(attackers, "attack")
+		defenderRollMap := s.createRollMap(defenders, "defend")
 
 		/**
 		 * Perform Roll Map Adjustments.
@@ -243,15 +442,15 @@ func resolveConflict(a, d map[string]int, ool []string) *ConflictProfile {
 		 */
 
 		// Reduce the number of rolls at the AAA hitValue
-		defenderRollMap.RemoveUnits(defenders, []string{"aaa", "raaa", "aag"}, "defend")
+		defenderRollMap.RemoveUnits(s.units, defenders, []string{"aaa", "raaa", "aag"}, "defend")
 
 		// We need to reduce the number of rolls in the roll map to account for
 		// the subs that have already attacked.
 		if attackerCanSuprise {
-			attackerRollMap.RemoveUnits(attackers, subs, "attack")
+			attackerRollMap.RemoveUnits(s.units, attackers, s.subs, "attack")
 		}
 		if defenderCanSuprise {
-			defenderRollMap.RemoveUnits(defenders, subs, "defend")
+			defenderRollMap.RemoveUnits(s.units, defenders, s.subs, "defend")
 		}
 
 		/**
@@ -269,30 +468,37 @@ func resolveConflict(a, d map[string]int, ool []string) *ConflictProfile {
 
 		// Aircraft should only roll if there are units that they are able to
 		// hit
-		if !hasOnlySubs(defenders) || hasUnit(attackers, "des") {
-			attackerAircraftHits = rollAircraft(attackers, "attack")
+		if !s.hasOnlySubs(defenders) || hasUnit(attackers, "des") {
+			s.recordPhase(PhaseAircraft, true)
+			attackerAircraftHits = s.rollAircraft(attackers, "attack")
+			s.recordHits(attackerAircraftHits)
 		}
 
 		// Remove the aircraft from the roll map so we don't roll for them in
 		// the later stages
-		attackerRollMap.RemoveUnits(attackers, aircraft, "attack")
+		attackerRollMap.RemoveUnits(s.units, attackers, s.aircraft, "attack")
 
 		// We need to know what the aircraft are able to hit. If they are limited
 		// they are unable to hit submarines
-		attackingAircraftOol := ool
-		if hasLimitedAircraft(attackers, defenders) {
-			attackingAircraftOol = noSubOol
+		attackingAircraftOol, attackingAircraftRestriction := ool.defender, AnyUnit
+		if s.hasLimitedAircraft(attackers, defenders) {
+			attackingAircraftOol, attackingAircraftRestriction = ool.defenderNoSub, NoSubs
 		}
 
 		// We need to roll the subs separately from the other units, since they
 		// cannot hit planes
-		if hasSub(attackers) && !attackerCanSuprise {
-			attackingSubHits = rollSubs(attackers, "attack")
-			attackerRollMap.RemoveUnits(attackers, subs, "attack")
+		if s.hasSub(attackers) && !attackerCanSuprise {
+			s.recordPhase(PhaseSubs, true)
+			attackingSubHits = s.rollSubs(attackers, "attack")
+			s.recordHits(attackingSubHits)
+			attackerRollMap.RemoveUnits(s.units, attackers, s.subs, "attack")
 		}
 
 		// Calculate and record the attacking hits for the round.
-		attackingHits += calculateHits(attackerRollMap)
+		s.recordPhase(PhaseMain, true)
+		mainHits := s.calculateHits(attackerRollMap)
+		attackingHits += mainHits
+		s.recordHits(mainHits)
 
 		/**
 		 * Roll Defenders Last
@@ -302,26 +508,32 @@ func resolveConflict(a, d map[string]int, ool []string) *ConflictProfile {
 
 		// Aircraft should only roll if there are units that they are able to
 		// hit
-		if !hasOnlySubs(attackers) || hasUnit(defenders, "des") {
-			defenderAircraftHits = rollAircraft(defenders, "defend")
+		if !s.hasOnlySubs(attackers) || hasUnit(defenders, "des") {
+			s.recordPhase(PhaseAircraft, false)
+			defenderAircraftHits = s.rollAircraft(defenders, "defend")
+			s.recordHits(defenderAircraftHits)
 		}
 
 		// Remove the aircraft from the roll map so we don't roll for them twice
-		defenderRollMap.RemoveUnits(defenders, aircraft, "defend")
+		defenderRollMap.RemoveUnits(s.units, defenders, s.aircraft, "defend")
 
 		// We need to know what the aircraft are able to hit. If they are limited
 		// they are unable to hit submarines
-		defendingAircraftOol := ool
-		if hasLimitedAircraft(defenders, attackers) {
-			defendingAircraftOol = noSubOol
+		defendingAircraftOol, defendingAircraftRestriction := ool.attacker, AnyUnit
+		if s.hasLimitedAircraft(defenders, attackers) {
+			defendingAircraftOol, defendingAircraftRestriction = ool.attackerNoSub, NoSubs
 		}
 
-		if hasSub(defenders) && !defenderCanSuprise {
-			defendingSubHits = rollSubs(defenders, "defend")
-			defenderRollMap.RemoveUnits(defenders, subs, "defend")
+		if s.hasSub(defenders) && !defenderCanSuprise {
+			s.recordPhase(PhaseSubs, false)
+			defendingSubHits = s.rollSubs(defenders, "defend")
+			s.recordHits(defendingSubHits)
+			defenderRollMap.RemoveUnits(s.units, defenders, s.subs, "defend")
 		}
 
-		defendingHits += calculateHits(defenderRollMap)
+		s.recordPhase(PhaseMain, false)
+		defendingHits += s.calculateHits(defenderRollMap)
+		s.recordHits(defendingHits)
 
 		totalDefenderHits := defendingHits + defenderSupriseHits + defendingSubHits + defenderAircraftHits
 		totalAttackerHits := attackingHits + attackerSupriseHits + attackingSubHits + attackerAircraftHits
@@ -339,68 +551,123 @@ func resolveConflict(a, d map[string]int, ool []string) *ConflictProfile {
 
 		// First take casualties from the submarines. Their hits can only be
 		// applied to surface ships
-		profile.DefenderIpcLoss += takeCasualties(defenders, attackingSubHits, ships) +
-			takeCasualties(defenders, attackerAircraftHits, attackingAircraftOol) +
-			takeCasualties(defenders, attackingHits, ool)
+		profile.DefenderIpcLoss += s.selectCasualties(false, defenders, attackers, attackingSubHits, s.ships, ShipsOnly) +
+			s.selectCasualties(false, defenders, attackers, attackerAircraftHits, attackingAircraftOol, attackingAircraftRestriction) +
+			s.selectCasualties(false, defenders, attackers, attackingHits, ool.defender, AnyUnit)
 
-		profile.AttackerIpcLoss += takeCasualties(attackers, defendingSubHits, ships) +
-			takeCasualties(attackers, defenderAircraftHits, defendingAircraftOol) +
-			takeCasualties(attackers, defendingHits, ool)
+		profile.AttackerIpcLoss += s.selectCasualties(true, attackers, defenders, defendingSubHits, s.ships, ShipsOnly) +
+			s.selectCasualties(true, attackers, defenders, defenderAircraftHits, defendingAircraftOol, defendingAircraftRestriction) +
+			s.selectCasualties(true, attackers, defenders, defendingHits, ool.attacker, AnyUnit)
 
+		profile.RoundStates = append(profile.RoundStates, s.roundState(attackers, defenders))
 	}
 
-	// Record some more data to the profile
-	profile.Rounds = len(profile.DefenderHits)
-
-	if len(attackers) > 0 {
-		profile.AttackerUnitsRemaining = formationToSortedSlice(attackers)
-	}
-	if len(defenders) > 0 {
-		profile.DefenderUnitsRemaining = formationToSortedSlice(defenders)
+	// Withdrawn attackers survive the conflict, the attacker having retreated
+	// unless seaborne units are left
+	if len(withdrawn) > 0 {
+		if len(attackers) == 0 && profile.Outcome != Unresolved {
+			profile.Outcome = AttackerRetreat
+		}
+		for alias, n := range withdrawn {
+			attackers[alias] += n
+		}
 	}
 
 	// We record the conflict outcome onto the profile. Marked by
+	//  3: Unresolved
+	//  2: Attacker Retreat
 	//  1: Attacker Victory
 	//  0: Draw
 	// -1: Defender Victory
-	if len(attackers) > 0 && len(defenders) > 0 {
-		profile.Outcome = 0
+	if profile.Outcome == AttackerRetreat || profile.Outcome == Unresolved {
+		// The outcome was decided during the conflict
+	} else if len(attackers) > 0 && len(defenders) > 0 {
+		profile.Outcome = Draw
 	} else if len(attackers) == 0 && len(defenders) == 0 {
-		profile.Outcome = 0
+		profile.Outcome = Draw
 	} else if len(attackers) > 0 {
-		profile.Outcome = 1
+		profile.Outcome = AttackerWin
 	} else if len(defenders) > 0 {
-		profile.Outcome = -1
+		profile.Outcome = DefenderWin
+	}
+
+	// Once the conflict is over, carrier based aircraft left without a
+	// carrier to land on are lost. They do not change the outcome.
+	if profile.Outcome != Unresolved {
+		profile.AttackerIpcLoss += s.crashCarrierAircraft(attackers)
+		profile.DefenderIpcLoss += s.crashCarrierAircraft(defenders)
+	}
+
+	// Record some more data to the profile
+	s.recordEndBattle(profile.Outcome)
+	profile.Rounds = len(profile.DefenderHits)
+	profile.TerritoryCaptured = profile.Outcome == AttackerWin && s.hasGroundUnits(attackers)
+
+	if len(attackers) > 0 {
+		profile.AttackerUnitsRemaining = formationToSortedSlice(attackers)
+	}
+	if len(defenders) > 0 {
+		profile.DefenderUnitsRemaining = formationToSortedSlice(defenders)
+	}
+	if len(attackerSubmerged) > 0 {
+		profile.AttackerUnitsSubmerged = formationToSortedSlice(attackerSubmerged)
+	}
+	if len(defenderSubmerged) > 0 {
+		profile.DefenderUnitsSubmerged = formationToSortedSlice(defenderSubmerged)
+	}
+	if len(bombarding) > 0 {
+		profile.AttackerUnitsBombarding = formationToSortedSlice(bombarding)
 	}
 
 	return profile
 
 }
 
-func isYGGame() bool {
-	return activeGame == "deluxe" || activeGame == "1940deluxe"
+func (s *Simulator) isYGGame() bool {
+	return s.game == "deluxe" || s.game == "1940deluxe"
+}
+
+// dieSides is the number of sides of the die used by the game. Rolls at 6
+// normally, but deluxe rolls an 8 sided die.
+func (s *Simulator) dieSides() int {
+	if s.isYGGame() {
+		return 8
+	}
+	return 6
 }
 
 // rollDie functions as a random number generator Rolls at 6 normally, but
-// deluxe rolls an 8 sided die. This needs a good refactor.
-func rollDie() int {
-	rollBase := 6
-	if isYGGame() {
-		rollBase = 8
+// deluxe rolls an 8 sided die. Rolled by the simulator's dice when they are
+// able to roll a face.
+func (s *Simulator) rollDie() int {
+	if d, ok := s.dice.(FaceDice); ok {
+		return d.Face(s.dieSides())
 	}
-	return rand.Intn(rollBase) + 1
+
+	return s.rng.Intn(s.dieSides()) + 1
 }
 
 // multiRoll will roll a number of dice at a specific hitValue, returning the
 // number of times the result of the die roll, was a hit according to the
 // hitValue
-func multiRoll(num, hitValue int) (hits int) {
+func (s *Simulator) multiRoll(num, hitValue int) (hits int) {
+	if s.dice != nil {
+		hits = s.dice.Roll(num, hitValue, s.dieSides())
+		s.recordRoll(num, hitValue, nil, hits)
+		return hits
+	}
+
+	var faces []int
 	for i := 0; i < num; i++ {
-		result := rollDie()
+		result := s.rollDie()
 		if result <= hitValue {
 			hits++
 		}
+		if s.trace != nil {
+			faces = append(faces, result)
+		}
 	}
+	s.recordRoll(num, hitValue, faces, hits)
 
 	return hits
 }
@@ -408,21 +675,19 @@ func multiRoll(num, hitValue int) (hits int) {
 // createRollMap will generate a RollMap from a given map of unit aliasas and
 // number of them. Calculates the roll map with a given "mode", specifically,
 // "attack" or "defend"
-func createRollMap(f map[string]int, mode string) (rollMap RollMap) {
-	for alias, n := range f {
+func (s *Simulator) createRollMap(f map[string]int, mode string) (rollMap RollMap) {
+	for alias := range f {
 		var hitValue int
 
 		shotsAtPlusOne := 0
 		totalNumUnits := numAllUnitsInFormation(f, realAlias(alias))
 
-		hasModifiedUnits := totalNumUnits > n
-		isModifiedUnit := strings.HasPrefix(alias, "-") || strings.HasPrefix(alias, "+")
-
-		if hasModifiedUnits && isModifiedUnit {
+		// Every version of a unit is rolled for together, once.
+		if alias != firstUnitKey(f, realAlias(alias)) {
 			continue
 		}
 
-		unit := activeUnits.Find(realAlias(alias))
+		unit := s.units.Find(realAlias(alias))
 
 		if mode == "attack" {
 			hitValue = unit.Attack
@@ -451,23 +716,74 @@ func createRollMap(f map[string]int, mode string) (rollMap RollMap) {
 }
 
 // calculateHits tallys the total number of hits for a map of units, returning
-// the total number of hits.
-func calculateHits(rollMap RollMap) (hits int) {
+// the total number of hits. Under Low Luck the hit values of the whole RollMap
+// are summed, every full die is a hit and only the remainder is rolled.
+func (s *Simulator) calculateHits(rollMap RollMap) (hits int) {
+	if s.lowLuck {
+		return s.lowLuckHits(rollMap)
+	}
+
+	return s.rollHits(rollMap)
+}
+
+// calculateSpecialHits tallys the hits of the special rolls, AAA, kamikaze and
+// multi roll units. These only use Low Luck when the simulator is set to apply
+// Low Luck to special rolls too.
+func (s *Simulator) calculateSpecialHits(rollMap RollMap) (hits int) {
+	if s.lowLuck && s.lowLuckSpecial {
+		return s.lowLuckHits(rollMap)
+	}
+
+	return s.rollHits(rollMap)
+}
+
+// rollHits rolls a die for every roll in the RollMap, returning the number of
+// hits.
+func (s *Simulator) rollHits(rollMap RollMap) (hits int) {
 	for _, m := range rollMap {
 		// If a map doesn't have a hit value, we don't need to roll for it.
 		if m.hitValue == 0 {
 			continue
 		}
 
-		hits += multiRoll(m.num, m.hitValue)
+		hits += s.multiRoll(m.num, m.hitValue)
+	}
+
+	return hits
+}
+
+// lowLuckHits sums the hit values of the RollMap, scoring a hit for every full
+// die worth of power and rolling a single die for the remainder.
+func (s *Simulator) lowLuckHits(rollMap RollMap) (hits int) {
+	var power int
+	for _, m := range rollMap {
+		power += m.hitValue * m.num
+	}
+
+	sides := s.dieSides()
+	hits = power / sides
+	if hits > 0 {
+		s.recordRoll(0, power-power%sides, nil, hits)
+	}
+	if power%sides > 0 {
+		hits += s.multiRoll(1, power%sides)
 	}
 
 	return hits
 }
 
+// multiRollPower is the Low Luck hit value of a multi roll unit, the chance of
+// any of its dice hitting expressed in sides of a single die.
+func (s *Simulator) multiRollPower(hitValue, dice int) int {
+	sides := float64(s.dieSides())
+	miss := math.Pow(1-float64(hitValue)/sides, float64(dice))
+
+	return int(math.Floor(sides*(1-miss) + .5))
+}
+
 // getAAARollMap uses the attackers and defenders to calculate the number of
 // rolls that should be given to the AAA
-func getAAARollMap(a, d map[string]int) RollMap {
+func (s *Simulator) getAAARollMap(a, d map[string]int) RollMap {
 	var numPlanes int
 
 	// We need to determine if we are rolling for standard AAA or if we have
@@ -478,14 +794,14 @@ func getAAARollMap(a, d map[string]int) RollMap {
 	}
 
 	// Refactor some day this is awful
-	if isYGGame() {
+	if s.isYGGame() {
 		unitAlias = "aag"
 	}
 
 	numAAA := numAllUnitsInFormation(d, unitAlias)
 
 	// Determine how many planes the attacker has in it's fleet
-	for _, plane := range aircraft {
+	for _, plane := range s.aircraft {
 		numPlanes += numAllUnitsInFormation(a, plane)
 	}
 
@@ -502,12 +818,12 @@ func getAAARollMap(a, d map[string]int) RollMap {
 		unitAlias: numAAAShots,
 	}
 
-	return createRollMap(aaaFormation, "defend")
+	return s.createRollMap(aaaFormation, "defend")
 }
 
 // takeCasualties removed units from the map in order of their value, and returns
 // the total cost of the casualties taken.
-func takeCasualties(f map[string]int, num int, ool []string) int {
+func (s *Simulator) takeCasualties(f map[string]int, num int, ool []string) int {
 	// If we get a casualty number of 0 just leave that shit alone.
 	if num <= 0 {
 		return 0
@@ -516,8 +832,8 @@ func takeCasualties(f map[string]int, num int, ool []string) int {
 	var ipcValueOfCasualties int
 	// Find the units in order of their casualty value
 
-	if hasUndamagedCapitalShips(f) {
-		capitalShipDamage := damageCapitalShips(f, num)
+	if s.hasUndamagedCapitalShips(f) {
+		capitalShipDamage := s.damageCapitalShips(f, num)
 		num = num - capitalShipDamage
 	}
 
@@ -527,6 +843,21 @@ func takeCasualties(f map[string]int, num int, ool []string) int {
 			break
 		}
 
+		// Carrier based aircraft are taken before the other aircraft of
+		// their kind, as they may well be lost along with their carrier.
+		if numCarried, ok := f["*"+u]; ok {
+			if numCarried <= num {
+				num = num - numCarried
+				ipcValueOfCasualties += s.lossValue(s.units.Find(u)) * numCarried
+				delete(f, "*"+u)
+			} else {
+				ipcValueOfCasualties += s.lossValue(s.units.Find(u)) * num
+				f["*"+u] = numCarried - num
+				num = 0
+				break
+			}
+		}
+
 		// The unitIndex is the index within the passed in unit map.
 		// Depending on if the unit has a prefix or not, it's index may or may
 		// not be the unit alias directly.
@@ -563,11 +894,11 @@ func takeCasualties(f map[string]int, num int, ool []string) int {
 		// from the unit set and reduce our number to 0.
 		if numUnits <= num {
 			num = num - numUnits
-			ipcValueOfCasualties += (activeUnits.Find(unmodifiedIndex).Cost * numUnits)
+			ipcValueOfCasualties += (s.lossValue(s.units.Find(unmodifiedIndex)) * numUnits)
 			// Remove the unit from the unit set completely.
 			delete(f, unitIndex)
 		} else {
-			ipcValueOfCasualties += (activeUnits.Find(unmodifiedIndex).Cost * num)
+			ipcValueOfCasualties += (s.lossValue(s.units.Find(unmodifiedIndex)) * num)
 			f[unitIndex] = f[unitIndex] - num
 			num = 0
 		}
@@ -576,20 +907,87 @@ func takeCasualties(f map[string]int, num int, ool []string) int {
 	return ipcValueOfCasualties
 }
 
+// crashCarrierAircraft destroys the carrier based aircraft of the formation
+// which are left without room on a carrier, the cheapest first. A damaged
+// carrier has no room for aircraft. Returns the IPC value of the aircraft lost.
+func (s *Simulator) crashCarrierAircraft(f map[string]int) int {
+	var capacity int
+	carried := map[string]int{}
+	for alias, n := range f {
+		if !strings.HasPrefix(alias, "-") {
+			capacity += s.units.Find(realAlias(alias)).CarrierCapacity * n
+		}
+		if strings.HasPrefix(alias, "*") {
+			carried[realAlias(alias)] = n
+		}
+	}
+
+	excess := getTotalNumUnits(carried) - capacity
+	if excess <= 0 {
+		return 0
+	}
+
+	loss := s.takeCasualties(carried, excess, s.aircraft)
+	for _, alias := range s.aircraft {
+		if carried[alias] > 0 {
+			f["*"+alias] = carried[alias]
+		} else {
+			delete(f, "*"+alias)
+		}
+	}
+
+	return loss
+}
+
+// lossValue is the number of IPCs lost with a unit, transports losing their
+// cargo along with them.
+func (s *Simulator) lossValue(unit *Unit) int {
+	if unit.IsTransport {
+		return unit.Cost + s.cargoValue
+	}
+
+	return unit.Cost
+}
+
+// hasOnlyDefenselessTransports returns whether every unit of the formation is
+// a transport unable to defend itself.
+func (s *Simulator) hasOnlyDefenselessTransports(f map[string]int) bool {
+	for alias := range f {
+		if !sliceHasValue(s.defenselessTransports, realAlias(alias)) {
+			return false
+		}
+	}
+
+	return len(f) > 0
+}
+
+// canScoreHits returns whether the formation has a unit able to hit the enemy
+// in combat. AAA and defenseless transports never do.
+func (s *Simulator) canScoreHits(f map[string]int) bool {
+	for alias := range f {
+		unit := s.units.Find(realAlias(alias))
+		if unit.Attack > 0 && !unit.IsAAA && !sliceHasValue(s.defenselessTransports, unit.Alias) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // isResolved lets us know if the battle is over.
-func isResolved(attackers, defenders map[string]int) (resolved bool) {
+func (s *Simulator) isResolved(attackers, defenders map[string]int) (resolved bool) {
 	if len(attackers) == 0 || len(defenders) == 0 {
 		return true
 	}
 
-	defenderHasSub := hasSub(defenders)
+	defenderHasSub := s.hasSub(defenders)
 
-	if hasOnlyPlanes(attackers) && (len(defenders) == 1 && defenderHasSub) {
+	if s.hasOnlyPlanes(attackers) && (len(defenders) == 1 && defenderHasSub) {
 		return true
 	}
 
-	attackerHasSub := hasSub(attackers)
-	if hasOnlyPlanes(defenders) && (len(attackers) == 1 && attackerHasSub) {
+	attackerHasSub := s.hasSub(attackers)
+	if s.hasOnlyPlanes(defenders) && (len(attackers) == 1 && attackerHasSub) {
 		return true
 	}
 
@@ -602,8 +1000,8 @@ func isResolved(attackers, defenders map[string]int) (resolved bool) {
 // were damaged.
 // @TODO There is an error in here. We need to be able to assign damage to a
 // reserved capital ship. How do we handle a `-+bat` or a `+-bat`
-func damageCapitalShips(units map[string]int, hits int) (numDamaged int) {
-	for _, ship := range capitalShips {
+func (s *Simulator) damageCapitalShips(units map[string]int, hits int) (numDamaged int) {
+	for _, ship := range s.capitalShips {
 		// If we don't have this capital ship, move on
 		if _, ok := units[ship]; !ok {
 			continue
@@ -634,9 +1032,9 @@ func damageCapitalShips(units map[string]int, hits int) (numDamaged int) {
 
 // hasUndamagedCapitalShips will return whether or not a map of units has an
 // undamaged capital ship.
-func hasUndamagedCapitalShips(units map[string]int) bool {
+func (s *Simulator) hasUndamagedCapitalShips(units map[string]int) bool {
 	var a bool
-	for _, ship := range capitalShips {
+	for _, ship := range s.capitalShips {
 
 		// Find an undamaged capital ship. Can be reserved But can NOT be
 		// damaged obvs. So don't use the HasUnits method here
@@ -658,8 +1056,8 @@ func canKamikaze(units map[string]int) bool {
 
 // attackerCanSupriseAttack lets us know if a conflict allows for a sub suprise
 // attack by an attacker
-func canSupriseAttack(a, b map[string]int) bool {
-	aHasSub := hasSub(a)
+func (s *Simulator) canSupriseAttack(a, b map[string]int) bool {
+	aHasSub := s.hasSub(a)
 	bHasDes := hasUnit(b, "des")
 
 	return aHasSub && !bHasDes
@@ -669,15 +1067,15 @@ func canSupriseAttack(a, b map[string]int) bool {
 // bombardment. There is an issue here, if an end user sends through a ship as
 // a bombard against a land unit, the conflict will proceed like a normal
 // conflict. I'm calling this, however, "not a bug" but a user error.
-func canBombard(units map[string]int) bool {
+func (s *Simulator) canBombard(units map[string]int) bool {
 
-	return hasGroundUnits(units) && hasBombardShips(units)
+	return s.hasGroundUnits(units) && s.hasBombardShips(units)
 }
 
 // canUseAAA lets the program know if the current set of attackers and
 // defenders are capable of using AAA before the start of the battle
-func canUseAAA(attackers, defenders map[string]int) bool {
-	return (hasUnit(defenders, "aaa") || hasUnit(defenders, "raaa") || hasUnit(defenders, "aag")) && hasAircraft(attackers)
+func (s *Simulator) canUseAAA(attackers, defenders map[string]int) bool {
+	return (hasUnit(defenders, "aaa") || hasUnit(defenders, "raaa") || hasUnit(defenders, "aag")) && s.hasAircraft(attackers)
 }
 
 // getTotalNumUnits returns the total number of units within a map of units
@@ -690,74 +1088,101 @@ func getTotalNumUnits(u map[string]int) (num int) {
 
 // rollForUnit rolls all the units identified by a particular alias and returns
 // the number of hits.
-func rollForUnit(f map[string]int, unit *Unit, mode string) (hits int) {
+func (s *Simulator) rollForUnit(f map[string]int, unit *Unit, mode string) (hits int) {
+	if mode == "attack" && unit.MultiRoll > 0 {
+		numUnits := numAllUnitsInFormation(f, unit.Alias)
+		return s.rollMultiRollUnits(map[string]int{unit.Alias: numUnits}, mode)
+	}
+
+	return s.calculateHits(s.unitRollMap(f, unit, mode))
+}
+
+// unitRollMap creates the RollMap of all the units identified by a particular
+// alias. Multi roll units are only given a RollMap under Low Luck, rolling at
+// their multiRollPower.
+func (s *Simulator) unitRollMap(f map[string]int, unit *Unit, mode string) (rm RollMap) {
 	numUnits := numAllUnitsInFormation(f, unit.Alias)
 
-	if mode == "attack" {
-		if unit.MultiRoll > 0 {
-			hits = rollMultiRollUnits(map[string]int{unit.Alias: numUnits}, mode)
-		} else {
-			rm := RollMap{}
-			var unitsAtPlusOne int
-			if unit.PlusOneRolls != nil {
-				unitsAtPlusOne = unit.PlusOneRolls(f)
-				numUnits = numUnits - unitsAtPlusOne
-			}
+	if mode != "attack" {
+		return s.createRollMap(map[string]int{unit.Alias: numUnits}, mode)
+	}
 
-			if unitsAtPlusOne > 0 {
-				rm = rm.AddRoll(unit.Attack+1, unitsAtPlusOne)
-			}
-			if numUnits > 0 {
-				rm = rm.AddRoll(unit.Attack, numUnits)
-			}
-			hits = calculateHits(rm)
-		}
-	} else {
-		rm := createRollMap(map[string]int{unit.Alias: numUnits}, mode)
-		hits = calculateHits(rm)
+	if unit.MultiRoll > 0 {
+		return rm.AddRoll(s.multiRollPower(unit.Attack, unit.MultiRoll), numUnits)
 	}
 
-	return hits
+	var unitsAtPlusOne int
+	if unit.PlusOneRolls != nil {
+		unitsAtPlusOne = unit.PlusOneRolls(f)
+		numUnits = numUnits - unitsAtPlusOne
+	}
+
+	if unitsAtPlusOne > 0 {
+		rm = rm.AddRoll(unit.Attack+1, unitsAtPlusOne)
+	}
+	if numUnits > 0 {
+		rm = rm.AddRoll(unit.Attack, numUnits)
+	}
+
+	return rm
 }
 
-func rollForUnitSlice(f map[string]int, slice []string, mode string) (hits int) {
+// rollForUnitSlice rolls all the units within the slice and returns the number
+// of hits. Under Low Luck the units are rolled as a single RollMap, pooling
+// their power, multi roll units only joining the pool when Low Luck applies to
+// special rolls.
+func (s *Simulator) rollForUnitSlice(f map[string]int, slice []string, mode string) (hits int) {
+	var pool RollMap
 	for _, alias := range slice {
 		if hasUnit(f, alias) {
-			unit := activeUnits.Find(realAlias(alias))
-			hits += rollForUnit(f, unit, mode)
+			unit := s.units.Find(realAlias(alias))
+
+			isMultiRoll := mode == "attack" && unit.MultiRoll > 0
+			if !s.lowLuck || (isMultiRoll && !s.lowLuckSpecial) {
+				hits += s.rollForUnit(f, unit, mode)
+				continue
+			}
+
+			for _, rv := range s.unitRollMap(f, unit, mode) {
+				pool = pool.AddRoll(rv.hitValue, rv.num)
+			}
 		}
 	}
 
+	if len(pool) > 0 {
+		hits += s.calculateHits(pool)
+	}
+
 	return hits
 }
 
 // rollSubs is a convenienve method that rolls all the sub units and returns
 // the number of hits
-func rollSubs(a map[string]int, mode string) (hits int) {
-	return rollForUnitSlice(a, subs, mode)
+func (s *Simulator) rollSubs(a map[string]int, mode string) (hits int) {
+	return s.rollForUnitSlice(a, s.subs, mode)
 }
 
 // rollAircraft is a convenience method that rolls all the aircraft units and
 // returns the number of hits
-func rollAircraft(a map[string]int, mode string) (hits int) {
-	return rollForUnitSlice(a, aircraft, mode)
+func (s *Simulator) rollAircraft(a map[string]int, mode string) (hits int) {
+	return s.rollForUnitSlice(a, s.aircraft, mode)
 }
 
 // rollMultiRollUnits rolls for all the units who get multiple dice per attack
 // roll. Selecting the highest of the die to score a hit.
-func rollMultiRollUnits(a map[string]int, mode string) (hits int) {
-	for _, alias := range multiRollUnits {
+func (s *Simulator) rollMultiRollUnits(a map[string]int, mode string) (hits int) {
+	for _, alias := range s.multiRollUnits {
 		if hasUnit(a, alias) {
 			// We must run each of these units separately to keep track
 			// of their rolls. So we will iterate as many units as we have.
 			iterations := numAllUnitsInFormation(a, alias)
-			numDie := activeUnits.Find(realAlias(alias)).MultiRoll
+			numDie := s.units.Find(realAlias(alias)).MultiRoll
 			for i := 0; i < iterations; i++ {
 				// Maybe a little bit of a hack. create a roll map for each
 				// iteration through. if any hits come back, record just 1 hit.
 				// since we are rolling multiple die but for only one unit.
-				rm := createRollMap(map[string]int{alias: numDie}, mode)
-				h := calculateHits(rm)
+				rm := s.createRollMap(map[string]int{alias: numDie}, mode)
+				h := s.rollHits(rm)
 				if h > 0 {
 					hits++
 				}
@@ -770,7 +1195,7 @@ func rollMultiRollUnits(a map[string]int, mode string) (hits int) {
 
 // conflictIsAutoKill returns whether or not the defender has any units
 // capable of putting up a defensive hit. AAA is not a defending unit.
-func conflictIsAutoKill(d, a map[string]int, firstRound bool) (autoKill bool) {
+func (s *Simulator) conflictIsAutoKill(d, a map[string]int, firstRound bool) (autoKill bool) {
 	autoKill = true
 	for alias := range d {
 		alias = realAlias(alias)
@@ -785,7 +1210,7 @@ func conflictIsAutoKill(d, a map[string]int, firstRound bool) (autoKill bool) {
 			// If we have AAA and there are aircraft or there are no attackers
 			// it is not an auto kill situation, otherwise aaa defenders is an
 			// autokill
-			if hasAircraft(a) || len(a) == 0 {
+			if s.hasAircraft(a) || len(a) == 0 {
 				autoKill = false
 				break
 			}
@@ -793,7 +1218,7 @@ func conflictIsAutoKill(d, a map[string]int, firstRound bool) (autoKill bool) {
 			continue
 		}
 
-		if activeUnits.Find(alias).Defend > 0 {
+		if s.units.Find(alias).Defend > 0 {
 			autoKill = false
 			break
 		}
@@ -802,30 +1227,48 @@ func conflictIsAutoKill(d, a map[string]int, firstRound bool) (autoKill bool) {
 	return autoKill
 }
 
-// reserveHighestValueLandUnit will assign the highest value unit in the units
-// map as a reserved unit. Specifically by adding the "+" prefix to the unit
-// alias. This unit will be taken last in conflict.
-func reserveHighestValueLandUnit(units map[string]int) {
-	// Iterate through the landTroops in reverse
-	for i := len(landTroops) - 1; i >= 0; i-- {
-		// If we have this land troop in our map, we need to reserve it.
-		if _, ok := units[landTroops[i]]; ok {
+// keptLandUnits removes the cheapest n land units from the formation, returning
+// the units removed
+func (s *Simulator) keptLandUnits(f map[string]int, n int) map[string]int {
+	kept := map[string]int{}
+	for _, alias := range s.landTroops {
+		for _, key := range []string{alias, "+" + alias} {
+			k := f[key]
+			if k > n {
+				k = n
+			}
+			if k == 0 {
+				continue
+			}
 
-			units[landTroops[i]] = units[landTroops[i]] - 1
-			units["+"+landTroops[i]] = 1
-			if units[landTroops[i]] == 0 {
-				delete(units, landTroops[i])
+			kept[key] = k
+			f[key] -= k
+			if f[key] == 0 {
+				delete(f, key)
 			}
-			break
+			n -= k
 		}
 	}
+
+	return kept
+}
+
+// mustTakeTerritory returns the number of land units kept once the must take
+// territory flag is set to a, keep being the number kept before
+func mustTakeTerritory(a bool, keep int) int {
+	if !a {
+		return 0
+	}
+	if keep < 1 {
+		return 1
+	}
+
+	return keep
 }
 
 // sliceHasUnit let's me know if a slice of strings has a particular value
 func sliceHasUnit(s []string, alias string) bool {
-	if strings.HasPrefix(alias, "-") || strings.HasPrefix(alias, "+") {
-		alias = alias[1:]
-	}
+	alias = realAlias(alias)
 	for _, a := range s {
 		if a == alias {
 			return true
@@ -848,13 +1291,17 @@ func sliceHasValue(s []string, alias string) bool {
 // checkUnitValidity determines if all the passed in units are valid for the
 // particular game that is being simulated. If not valid, will return an error
 // with a message including the units that are invalid.
-func checkUnitValidity(p map[string]int) error {
+func (s *Simulator) checkUnitValidity(p map[string]int) error {
 	var invalid []string
 	for alias := range p {
-		if strings.HasPrefix(alias, "-") || strings.HasPrefix(alias, "+") {
-			alias = alias[1:]
+		// Only aircraft may be based on a carrier
+		if strings.HasPrefix(alias, "*") && !s.units.Find(realAlias(alias)).IsAircraft {
+			invalid = append(invalid, alias)
+			continue
 		}
-		if !activeUnits.HasUnit(alias) {
+
+		alias = realAlias(alias)
+		if !s.units.HasUnit(alias) {
 			invalid = append(invalid, alias)
 		}
 	}
@@ -867,9 +1314,9 @@ func checkUnitValidity(p map[string]int) error {
 }
 
 // hasOnlyPlanes returns true if the formation contains only planes
-func hasOnlyPlanes(u map[string]int) bool {
+func (s *Simulator) hasOnlyPlanes(u map[string]int) bool {
 	for alias := range u {
-		if has := sliceHasUnit(aircraft, alias); !has {
+		if has := sliceHasUnit(s.aircraft, alias); !has {
 			return false
 		}
 	}
@@ -878,9 +1325,9 @@ func hasOnlyPlanes(u map[string]int) bool {
 }
 
 // hasOnlySubs returns true if the formation contains only subs
-func hasOnlySubs(u map[string]int) bool {
+func (s *Simulator) hasOnlySubs(u map[string]int) bool {
 	for alias := range u {
-		if has := sliceHasUnit(subs, alias); !has {
+		if has := sliceHasUnit(s.subs, alias); !has {
 			return false
 		}
 	}
@@ -889,8 +1336,8 @@ func hasOnlySubs(u map[string]int) bool {
 }
 
 // hasGroundUnits returns true if the formation contains any ground units
-func hasGroundUnits(u map[string]int) bool {
-	for _, unit := range landTroops {
+func (s *Simulator) hasGroundUnits(u map[string]int) bool {
+	for _, unit := range s.landTroops {
 		if hasUnit(u, unit) {
 			return true
 		}
@@ -899,8 +1346,8 @@ func hasGroundUnits(u map[string]int) bool {
 }
 
 // hasSub returns true if the formation contains any submarines
-func hasSub(u map[string]int) bool {
-	for _, unit := range subs {
+func (s *Simulator) hasSub(u map[string]int) bool {
+	for _, unit := range s.subs {
 		if hasUnit(u, unit) {
 			return true
 		}
@@ -908,20 +1355,21 @@ func hasSub(u map[string]int) bool {
 	return false
 }
 
-// hasUnit determines if a unit exists in a formation. The unit may be damaged
-// or reserved and still return true.
+// hasUnit determines if a unit exists in a formation. The unit may be damaged,
+// reserved or carrier based and still return true.
 func hasUnit(units map[string]int, alias string) bool {
 	_, has := units[alias]
 	_, hasReserved := units["+"+alias]
 	_, hasDamaged := units["-"+alias]
+	_, hasCarried := units["*"+alias]
 
-	return has || hasReserved || hasDamaged
+	return has || hasReserved || hasDamaged || hasCarried
 
 }
 
 // hasAircraft returns true if the formation contains any aircraft
-func hasAircraft(u map[string]int) bool {
-	for _, unit := range aircraft {
+func (s *Simulator) hasAircraft(u map[string]int) bool {
+	for _, unit := range s.aircraft {
 		if hasUnit(u, unit) {
 			return true
 		}
@@ -930,8 +1378,8 @@ func hasAircraft(u map[string]int) bool {
 }
 
 // hasBombardShips returns true if the formation contains any aircraft
-func hasBombardShips(u map[string]int) bool {
-	for _, unit := range bombardShips {
+func (s *Simulator) hasBombardShips(u map[string]int) bool {
+	for _, unit := range s.bombardShips {
 		if hasUnit(u, unit) {
 			return true
 		}
@@ -945,17 +1393,18 @@ func deleteUnitFromFormation(formation map[string]int, unit string) {
 	delete(formation, unit)
 	delete(formation, "+"+unit)
 	delete(formation, "-"+unit)
+	delete(formation, "*"+unit)
 }
 
 // hasLimitedAircraft returns true if the first formation has aircraft which can
 // not hit subs in the second formation.
-func hasLimitedAircraft(a, b map[string]int) bool {
+func (s *Simulator) hasLimitedAircraft(a, b map[string]int) bool {
 
-	if !hasAircraft(a) {
+	if !s.hasAircraft(a) {
 		return false
 	}
 
-	if !hasSub(b) {
+	if !s.hasSub(b) {
 		return false
 	}
 
@@ -986,20 +1435,41 @@ func formationToSortedSlice(f map[string]int) []map[string]int {
 }
 
 // numAllUnitsInFormation return the TOTAL number of units matching a particular
-// alias within the formation. Including damaged and reserved units.
+// alias within the formation. Including damaged, reserved and carrier based
+// units.
 func numAllUnitsInFormation(formation map[string]int, alias string) (num int) {
 	num, _ = formation[alias]
 	reservedNum, _ := formation["+"+alias]
 	damagedNum, _ := formation["-"+alias]
+	carriedNum, _ := formation["*"+alias]
 
-	return num + reservedNum + damagedNum
+	return num + reservedNum + damagedNum + carriedNum
 }
 
 // realAlias returns the actual alias of a unit. Trimming any modifiers
 func realAlias(alias string) string {
-	if strings.HasPrefix(alias, "-") || strings.HasPrefix(alias, "+") {
+	if hasModifier(alias) {
 		alias = alias[1:]
 	}
 
 	return alias
 }
+
+// firstUnitKey returns the first of the keys a unit may be found under within
+// the formation, unmodified first followed by the damaged, reserved and carrier
+// based versions.
+func firstUnitKey(formation map[string]int, alias string) string {
+	for _, key := range []string{alias, "-" + alias, "+" + alias, "*" + alias} {
+		if _, ok := formation[key]; ok {
+			return key
+		}
+	}
+
+	return alias
+}
+
+// hasModifier returns whether the alias is prefixed by a designation, marking
+// the unit as damaged, reserved or carrier based
+func hasModifier(alias string) bool {
+	return strings.HasPrefix(alias, "-") || strings.HasPrefix(alias, "+") || strings.HasPrefix(alias, "*")
+}
@@ -7,6 +7,8 @@ import (
 )
 
 func TestIsResolvedFunction(t *testing.T) {
+	s := NewSimulator()
+
 	values := []struct {
 		attackers map[string]int
 		defenders map[string]int
@@ -19,13 +21,15 @@ func TestIsResolvedFunction(t *testing.T) {
 		{map[string]int{"sub": 2}, map[string]int{"fig": 4, "des": 1}, false},
 	}
 	for _, tt := range values {
-		if isResolved(tt.attackers, tt.defenders) != tt.result {
+		if s.isResolved(tt.attackers, tt.defenders) != tt.result {
 			t.Errorf("\nConflict Resolution marked incorrectly.\nattackers:%v\ndefenders: %v", tt.attackers, tt.defenders)
 		}
 	}
 }
 
 func TestCasualtyTaking(t *testing.T) {
+	s := NewSimulator()
+
 	values := []struct {
 		units     map[string]int
 		hits      int
@@ -38,7 +42,7 @@ func TestCasualtyTaking(t *testing.T) {
 		{map[string]int{"tan": 2, "fig": 1, "inf": 1, "art": 3}, 7, map[string]int{}},
 	}
 	for _, tt := range values {
-		takeCasualties(tt.units, tt.hits, baseOol)
+		s.takeCasualties(tt.units, tt.hits, s.baseOol)
 
 		if !reflect.DeepEqual(tt.aftermath, tt.units) {
 			t.Errorf("casualties did not take properly\nexpected: %v\nactual:%v", tt.aftermath, tt.units)
@@ -47,6 +51,8 @@ func TestCasualtyTaking(t *testing.T) {
 }
 
 func TestHasUndamagedCapitalShip(t *testing.T) {
+	s := NewSimulator()
+
 	values := []struct {
 		units      map[string]int
 		hasCapital bool
@@ -59,13 +65,15 @@ func TestHasUndamagedCapitalShip(t *testing.T) {
 		{map[string]int{"+bat": 1, "cru": 2, "sub": 1, "des": 3}, true},
 	}
 	for _, tt := range values {
-		if hasUndamagedCapitalShips(tt.units) != tt.hasCapital {
+		if s.hasUndamagedCapitalShips(tt.units) != tt.hasCapital {
 			t.Errorf("Unit's Capital Ships marked incorrectly\n%v\n", tt.units)
 		}
 	}
 }
 
 func TestCapitalShipDamage(t *testing.T) {
+	s := NewSimulator()
+
 	values := []struct {
 		units      map[string]int
 		hits       int
@@ -78,7 +86,7 @@ func TestCapitalShipDamage(t *testing.T) {
 		{map[string]int{"car": 1, "bat": 2, "cru": 2, "sub": 1, "des": 3}, 2, map[string]int{"-car": 1, "-bat": 1, "bat": 1, "cru": 2, "sub": 1, "des": 3}, 2},
 	}
 	for _, tt := range values {
-		numDamaged := damageCapitalShips(tt.units, tt.hits)
+		numDamaged := s.damageCapitalShips(tt.units, tt.hits)
 		if !reflect.DeepEqual(tt.aftermath, tt.units) {
 			t.Errorf("we did not damage the capital ships correctly\nexpected: %v\nactual: %v\n", tt.aftermath, tt.units)
 		}
@@ -89,6 +97,8 @@ func TestCapitalShipDamage(t *testing.T) {
 }
 
 func TestSupriseAttack(t *testing.T) {
+	s := NewSimulator()
+
 	values := []struct {
 		attackers   map[string]int
 		defenders   map[string]int
@@ -109,16 +119,18 @@ func TestSupriseAttack(t *testing.T) {
 	}
 
 	for _, tt := range values {
-		if canSupriseAttack(tt.attackers, tt.defenders) != tt.attackerCan {
+		if s.canSupriseAttack(tt.attackers, tt.defenders) != tt.attackerCan {
 			t.Errorf("the attacker suprise attack ability was not calculated correctly\nexpected: %v", tt.attackerCan)
 		}
-		if canSupriseAttack(tt.defenders, tt.attackers) != tt.defenderCan {
+		if s.canSupriseAttack(tt.defenders, tt.attackers) != tt.defenderCan {
 			t.Errorf("the attacker suprise attack ability was not calculated correctly\nexpected: %v", tt.defenderCan)
 		}
 	}
 }
 
 func TestCanBombard(t *testing.T) {
+	s := NewSimulator()
+
 	values := []struct {
 		units map[string]int
 		can   bool
@@ -134,31 +146,16 @@ func TestCanBombard(t *testing.T) {
 	}
 
 	for _, tt := range values {
-		if canBombard(tt.units) != tt.can {
+		if s.canBombard(tt.units) != tt.can {
 			t.Errorf("Units bombard not calculated correctly.\n%v", tt.units)
 		}
 	}
 
 }
 
-func TestReservationOfHighestValueLandUnit(t *testing.T) {
-	values := []struct {
-		units    map[string]int
-		expected map[string]int
-	}{
-		{map[string]int{"inf": 1, "art": 1, "tan": 1, "fig": 3}, map[string]int{"inf": 1, "art": 1, "+tan": 1, "fig": 3}},
-		{map[string]int{"inf": 1, "art": 1, "fig": 3}, map[string]int{"inf": 1, "+art": 1, "fig": 3}},
-	}
-	for _, tt := range values {
-		reserveHighestValueLandUnit(tt.units)
-		if !reflect.DeepEqual(tt.expected, tt.units) {
-			t.Errorf("reserving the highest value land unit doesn't work right\nexpected: %v\nactual: %v\n", tt.expected, tt.units)
-		}
-	}
-
-}
-
 func TestMecAndInfPlusOneFunc(t *testing.T) {
+	s := NewSimulator()
+
 	values := []struct {
 		units          map[string]int
 		numInfBoosted  int
@@ -182,9 +179,9 @@ func TestMecAndInfPlusOneFunc(t *testing.T) {
 		{map[string]int{"inf": 3, "imec": 3, "aart": 2, "fig": 4, "tan": 1}, 3, 0, 2},
 	}
 
-	inf := activeUnits.Find("inf")
-	mec := activeUnits.Find("mec")
-	imec := activeUnits.Find("imec")
+	inf := s.units.Find("inf")
+	mec := s.units.Find("mec")
+	imec := s.units.Find("imec")
 
 	for _, tt := range values {
 		if inf.PlusOneRolls(tt.units) != tt.numInfBoosted {
@@ -201,6 +198,8 @@ func TestMecAndInfPlusOneFunc(t *testing.T) {
 }
 
 func TestTacPlusOneFunc(t *testing.T) {
+	s := NewSimulator()
+
 	values := []struct {
 		units         map[string]int
 		numTacBoosted int
@@ -211,7 +210,7 @@ func TestTacPlusOneFunc(t *testing.T) {
 		{map[string]int{"tan": 2, "tac": 1}, 1},
 	}
 
-	tac := activeUnits.Find("tac")
+	tac := s.units.Find("tac")
 
 	for _, tt := range values {
 		if tac.PlusOneRolls(tt.units) != tt.numTacBoosted {
@@ -259,15 +258,14 @@ func TestRollMapper(t *testing.T) {
 		{"deluxe", map[string]int{"inf": 5, "hif": 3, "mnb": 1}, "defend", RollMap{{0, 1}, {1, 5}, {4, 3}}},
 	}
 	for _, tt := range values {
-		SetGame(tt.game)
+		s := NewSimulator(WithGame(tt.game))
 
-		rmap := createRollMap(tt.units, tt.mode)
+		rmap := s.createRollMap(tt.units, tt.mode)
 
 		if !reflect.DeepEqual(tt.expected, rmap) {
 			t.Errorf("roll map did not generate correctly\nexpected:%v\nactual:%v", tt.expected, rmap)
 		}
 	}
-	SetGame("1940")
 }
 
 func TestSetOol(t *testing.T) {
@@ -275,7 +273,7 @@ func TestSetOol(t *testing.T) {
 
 	SetBaseOol(testOol)
 
-	if !reflect.DeepEqual(testOol, baseOol) {
+	if !reflect.DeepEqual(testOol, defaultSimulator.baseOol) {
 		t.Errorf("setting the baseOol failed")
 	}
 }
@@ -296,18 +294,18 @@ func TestUnitValidity(t *testing.T) {
 	}
 
 	for _, tt := range values {
-		SetGame(tt.game)
-		err := checkUnitValidity(tt.units)
+		s := NewSimulator(WithGame(tt.game))
+		err := s.checkUnitValidity(tt.units)
 		if (err == nil) != tt.valid {
 			t.Errorf("Unit validity was not determined correctly for game %v\nunits: %v\nmessage: %v", tt.game, tt.units, err)
 		}
 	}
 
-	// Reset the game back to 1940
-	SetGame("1940")
 }
 
 func TestHasLimitedAircraft(t *testing.T) {
+	s := NewSimulator()
+
 	values := []struct {
 		attackers map[string]int
 		defenders map[string]int
@@ -319,7 +317,7 @@ func TestHasLimitedAircraft(t *testing.T) {
 		{map[string]int{"des": 1, "bom": 1, "sub": 2}, map[string]int{"bat": 1, "fig": 1, "des": 1}, false},
 	}
 	for _, tt := range values {
-		if hasLimitedAircraft(tt.attackers, tt.defenders) != tt.result {
+		if s.hasLimitedAircraft(tt.attackers, tt.defenders) != tt.result {
 			t.Errorf("The need to limit aircraft not calculated correctly\nattackers: %v\ndefenders: %v", tt.attackers, tt.defenders)
 		}
 	}
@@ -348,6 +346,8 @@ func TestNumAllUnitsInFormation(t *testing.T) {
 }
 
 func TestHasOnlyPlanes(t *testing.T) {
+	s := NewSimulator()
+
 	values := []struct {
 		formation map[string]int
 		result    bool
@@ -359,7 +359,7 @@ func TestHasOnlyPlanes(t *testing.T) {
 		{map[string]int{"inf": 1, "fig": 3, "+bom": 1}, false},
 	}
 	for _, tt := range values {
-		actual := hasOnlyPlanes(tt.formation)
+		actual := s.hasOnlyPlanes(tt.formation)
 		if actual != tt.result {
 			t.Errorf("The only planes result was incorrect\nunits: %v\nresult: %v", tt.formation, actual)
 		}
@@ -367,6 +367,8 @@ func TestHasOnlyPlanes(t *testing.T) {
 }
 
 func TestCanUseAAA(t *testing.T) {
+	s := NewSimulator()
+
 	values := []struct {
 		attackers map[string]int
 		defenders map[string]int
@@ -382,7 +384,7 @@ func TestCanUseAAA(t *testing.T) {
 		{map[string]int{"fig": 2, "bom": 1, "tan": 3}, map[string]int{"raaa": 1, "art": 2, "inf": 2}, true},
 	}
 	for _, tt := range values {
-		if canUseAAA(tt.attackers, tt.defenders) != tt.result {
+		if s.canUseAAA(tt.attackers, tt.defenders) != tt.result {
 			t.Errorf("CanUseAAA marked incorrectly.\nattackers:%v\ndefenders: %v", tt.attackers, tt.defenders)
 		}
 	}
@@ -408,6 +410,8 @@ func TestKamikaze(t *testing.T) {
 }
 
 func TestMultiRollUnits(t *testing.T) {
+	s := NewSimulator()
+
 	values := []struct {
 		formation map[string]int
 		randSeed  int64
@@ -419,8 +423,8 @@ func TestMultiRollUnits(t *testing.T) {
 		{map[string]int{"hbom": 2}, 28, 2},
 	}
 	for _, tt := range values {
-		rand.Seed(tt.randSeed)
-		hits := rollMultiRollUnits(tt.formation, "attack")
+		s.rng = rand.New(rand.NewSource(tt.randSeed))
+		hits := s.rollMultiRollUnits(tt.formation, "attack")
 		if hits != tt.result {
 			t.Errorf("MultiRoll units are rolling incorrectly.\nformation: %v", tt.formation)
 		}
@@ -441,13 +445,302 @@ func TestRollDie(t *testing.T) {
 		{"deluxe", 19, 6},
 	}
 	for _, tt := range values {
-		SetGame(tt.game)
-		rand.Seed(tt.randSeed)
-		val := rollDie()
+		s := NewSimulator(WithGame(tt.game))
+		s.rng = rand.New(rand.NewSource(tt.randSeed))
+		val := s.rollDie()
 		if val != tt.result {
 			t.Errorf("RollDie did not return the correct result for seed\nexpected: %v\nactual: %v", tt.result, val)
 		}
 	}
-	// Reset the game back to 1940
-	SetGame("1940")
+}
+
+func TestLowLuck(t *testing.T) {
+	values := []struct {
+		game     string
+		special  bool
+		rollMap  RollMap
+		min, max int
+	}{
+		// 2 tanks and an infantry, 7 power is a guaranteed hit and a roll at 1
+		{"1940", false, RollMap{{1, 1}, {3, 2}}, 1, 2},
+		{"1940", false, RollMap{{3, 4}}, 2, 2},
+		{"deluxe", false, RollMap{{3, 4}}, 1, 2},
+		{"1940", false, RollMap{}, 0, 0},
+	}
+
+	for _, tt := range values {
+		s := NewSimulator(WithGame(tt.game), WithLowLuck(true))
+		seen := map[int]bool{}
+		for i := 0; i < 200; i++ {
+			seen[s.calculateHits(tt.rollMap)] = true
+		}
+		for hits := range seen {
+			if hits < tt.min || hits > tt.max {
+				t.Errorf("low luck hits were out of range\nrollMap: %v\nexpected: %v-%v\nactual: %v", tt.rollMap, tt.min, tt.max, hits)
+			}
+		}
+		if !seen[tt.min] || !seen[tt.max] {
+			t.Errorf("low luck did not roll the remainder\nrollMap: %v\nactual: %v", tt.rollMap, seen)
+		}
+	}
+}
+
+func TestLowLuckSpecialRolls(t *testing.T) {
+	// 6 AAA shots at 1 is exactly one hit under Low Luck
+	aaa := RollMap{{1, 6}}
+
+	rolled := NewSimulator(WithLowLuck(true))
+	lowLuck := NewSimulator(WithLowLuck(true), WithLowLuckSpecialRolls(true))
+
+	seen := map[int]bool{}
+	for i := 0; i < 200; i++ {
+		seen[rolled.calculateSpecialHits(aaa)] = true
+		if hits := lowLuck.calculateSpecialHits(aaa); hits != 1 {
+			t.Errorf("low luck special rolls should always hit once, actual: %v", hits)
+		}
+	}
+	if len(seen) < 2 {
+		t.Errorf("special rolls should be rolled when low luck does not apply to them\nactual: %v", seen)
+	}
+
+	// A heavy bomber hits on a 4 with two dice, 8 in 9 of the time, which
+	// rounds to a power of 5.
+	if power := lowLuck.multiRollPower(4, 2); power != 5 {
+		t.Errorf("multi roll power was not calculated correctly\nexpected: 5\nactual: %v", power)
+	}
+	hits := lowLuck.rollForUnitSlice(map[string]int{"hbom": 6}, lowLuck.aircraft, "attack")
+	if hits != 5 {
+		t.Errorf("low luck heavy bombers were not pooled\nexpected: 5\nactual: %v", hits)
+	}
+}
+
+func TestLowLuckExactSummaryUnsupported(t *testing.T) {
+	s := NewSimulator(WithLowLuck(true))
+	_, err := s.GetExactSummary(map[string]int{"inf": 1}, map[string]int{"inf": 1})
+	if _, ok := err.(*UnsupportedError); !ok {
+		t.Errorf("exact summary should be unsupported under low luck, actual: %v", err)
+	}
+}
+
+func TestMaxRounds(t *testing.T) {
+	attackers := map[string]int{"inf": 6, "art": 2, "tan": 2}
+	defenders := map[string]int{"inf": 8, "tan": 1}
+
+	s := NewSimulator(WithMaxRounds(2))
+	for i := 0; i < 200; i++ {
+		profile := s.resolveConflict(attackers, defenders, s.customizeOol(attackers, defenders))
+		if profile.Rounds > 2 {
+			t.Fatalf("conflict was fought past the maximum rounds\n%+v", profile)
+		}
+		if profile.Outcome == Unresolved && (len(profile.AttackerUnitsRemaining) == 0 || len(profile.DefenderUnitsRemaining) == 0) {
+			t.Fatalf("unresolved conflict did not record both sides remaining\n%+v", profile)
+		}
+	}
+
+	// The unresolved conflicts are counted from the profiles themselves
+	s = NewSimulator(WithMaxRounds(1))
+	profiles := make([]ConflictProfile, 1000)
+	var unresolved int
+	for i := range profiles {
+		profiles[i] = *s.resolveConflict(attackers, defenders, s.customizeOol(attackers, defenders))
+		if profiles[i].Outcome == Unresolved {
+			unresolved++
+		}
+	}
+	summary := generateSummary(profiles)
+
+	var attackerFormations, defenderFormations int
+	for _, n := range summary.UnresolvedAttackerUnitsRemaining {
+		attackerFormations += n
+	}
+	for _, n := range summary.UnresolvedDefenderUnitsRemaining {
+		defenderFormations += n
+	}
+
+	if summary.AverageRounds != 1 || unresolved == 0 || attackerFormations != unresolved || defenderFormations != unresolved ||
+		summary.UnresolvedPercentage != round(float64(unresolved)/10, 2) {
+		t.Errorf("unresolved conflicts were not summarized correctly\n%+v", summary)
+	}
+}
+
+func TestTransports(t *testing.T) {
+	values := []struct {
+		game      string
+		units     map[string]int
+		hits      int
+		aftermath map[string]int
+	}{
+		// Defenseless transports are taken after everything, reserved or not
+		{"1940", map[string]int{"tra": 2, "des": 1, "+cru": 1}, 2, map[string]int{"tra": 2}},
+		{"1942", map[string]int{"tra": 2, "sub": 1}, 2, map[string]int{"tra": 1}},
+		{"deluxe", map[string]int{"tra": 1, "des": 1, "sbm": 1}, 2, map[string]int{"tra": 1}},
+		{"1940deluxe", map[string]int{"tra": 2, "csr": 1}, 1, map[string]int{"tra": 2}},
+		// 1941 transports are fodder
+		{"1941", map[string]int{"tra": 2, "des": 1}, 2, map[string]int{"des": 1}},
+	}
+	for _, tt := range values {
+		s := NewSimulator(WithGame(tt.game))
+		s.takeCasualties(tt.units, tt.hits, s.customizeOol(tt.units, map[string]int{}).attacker)
+
+		if !reflect.DeepEqual(tt.aftermath, tt.units) {
+			t.Errorf("%v transports were not taken properly\nexpected: %v\nactual:%v", tt.game, tt.aftermath, tt.units)
+		}
+	}
+
+	// Lone defenseless transports are destroyed whichever side they are on,
+	// along with their cargo
+	s := NewSimulator(WithCargoValue(9))
+	transports, destroyer := map[string]int{"tra": 2}, map[string]int{"des": 1}
+	profile := s.resolveConflict(transports, destroyer, s.customizeOol(transports, destroyer))
+	if profile.Outcome != DefenderWin || profile.Rounds != 0 || profile.AttackerIpcLoss != 32 {
+		t.Errorf("attacking transports were not destroyed\n%+v", profile)
+	}
+	profile = s.resolveConflict(destroyer, transports, s.customizeOol(destroyer, transports))
+	if profile.Outcome != AttackerWin || profile.Rounds != 0 || profile.DefenderIpcLoss != 32 {
+		t.Errorf("defending transports were not destroyed\n%+v", profile)
+	}
+
+	exact, err := s.GetExactSummary(map[string]int{"tra": 2}, map[string]int{"des": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exact.DefenderWinPercentage != 100 || exact.AttackerAvgIpcLoss != 32 {
+		t.Errorf("exact summary did not destroy the transports\n%+v", exact)
+	}
+
+	// Only by an enemy able to fire, otherwise it is a draw
+	conflicts := []struct {
+		attackers map[string]int
+		defenders map[string]int
+		outcome   int
+		loss      int
+	}{
+		{map[string]int{"tra": 1}, map[string]int{"tra": 1}, Draw, 0},
+		{map[string]int{"car": 1}, map[string]int{"tra": 1}, Draw, 0},
+		{map[string]int{"sub": 1}, map[string]int{"tra": 1}, AttackerWin, 16},
+	}
+	for _, tt := range conflicts {
+		profile := s.resolveConflict(tt.attackers, tt.defenders, s.customizeOol(tt.attackers, tt.defenders))
+		if profile.Outcome != tt.outcome || profile.Rounds != 0 || profile.DefenderIpcLoss != tt.loss || profile.AttackerIpcLoss != 0 {
+			t.Errorf("%v against %v was not resolved properly\n%+v", tt.attackers, tt.defenders, profile)
+		}
+
+		exact, err := s.GetExactSummary(tt.attackers, tt.defenders)
+		if err != nil {
+			t.Fatal(err)
+		}
+		win, draw := exact.AttackerWinPercentage, exact.DrawPercentage
+		if (tt.outcome == Draw && draw != 100) || (tt.outcome == AttackerWin && win != 100) || exact.DefenderAvgIpcLoss != float64(tt.loss) {
+			t.Errorf("exact summary of %v against %v was not resolved properly\n%+v", tt.attackers, tt.defenders, exact)
+		}
+	}
+}
+
+func TestCarrierBasedAircraft(t *testing.T) {
+	s := NewSimulator()
+
+	values := []struct {
+		units     map[string]int
+		loss      int
+		aftermath map[string]int
+	}{
+		{map[string]int{"car": 1, "*fig": 3}, 10, map[string]int{"car": 1, "*fig": 2}},
+		// A damaged carrier can not land aircraft
+		{map[string]int{"-car": 1, "*fig": 1, "*tac": 1}, 21, map[string]int{"-car": 1}},
+		{map[string]int{"car": 1, "-car": 1, "*fig": 3}, 10, map[string]int{"car": 1, "-car": 1, "*fig": 2}},
+		{map[string]int{"fig": 2, "*fig": 1, "*tac": 1}, 21, map[string]int{"fig": 2}},
+	}
+	for _, tt := range values {
+		if loss := s.crashCarrierAircraft(tt.units); loss != tt.loss || !reflect.DeepEqual(tt.aftermath, tt.units) {
+			t.Errorf("carrier aircraft did not crash properly\nexpected: %v %v\nactual: %v %v", tt.loss, tt.aftermath, loss, tt.units)
+		}
+	}
+
+	// Carrier based aircraft are taken before the others of their kind
+	units := map[string]int{"fig": 1, "*fig": 1}
+	s.takeCasualties(units, 1, s.baseOol)
+	if !reflect.DeepEqual(units, map[string]int{"fig": 1}) {
+		t.Errorf("carrier based aircraft were not taken first\nactual: %v", units)
+	}
+
+	// A carrier with aircraft on board is taken after every other ship
+	ool := s.customizeOol(map[string]int{"bat": 1}, map[string]int{"car": 1, "*fig": 2, "bat": 1}).defender
+	if sliceIndex(ool, "car") < sliceIndex(ool, "bat") {
+		t.Errorf("carrier was not moved after the other ships\nactual: %v", ool)
+	}
+
+	// Only by the side whose aircraft it carries
+	ool = s.customizeOol(map[string]int{"car": 1, "bat": 1}, map[string]int{"car": 1, "*fig": 2, "bat": 1}).attacker
+	if sliceIndex(ool, "car") > sliceIndex(ool, "bat") {
+		t.Errorf("carrier was moved for the other side's aircraft\nactual: %v", ool)
+	}
+
+	if err := s.checkUnitValidity(map[string]int{"*inf": 1}); err == nil {
+		t.Errorf("only aircraft should be carrier based")
+	}
+
+	// The submarines can't hit the aircraft, which are lost with the carrier
+	s = NewSimulator(WithGame("1942"))
+	subs, carrier := map[string]int{"sub": 3}, map[string]int{"car": 1, "*fig": 2}
+	var draws int
+	for i := 0; i < 200; i++ {
+		profile := s.resolveConflict(subs, carrier, s.customizeOol(subs, carrier))
+		if profile.Outcome != Draw {
+			continue
+		}
+
+		draws++
+		if len(profile.DefenderUnitsRemaining) > 0 || profile.DefenderIpcLoss != 34 {
+			t.Fatalf("aircraft without a carrier were not lost\n%+v", profile)
+		}
+	}
+	if draws == 0 {
+		t.Errorf("the carrier was never sunk")
+	}
+}
+
+// sliceIndex returns the index of the value within the slice, -1 when missing
+func sliceIndex(s []string, v string) int {
+	for i, a := range s {
+		if a == v {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestOolProfiles(t *testing.T) {
+	values := []struct {
+		attacker OolProfile
+		defender OolProfile
+		units    map[string]int
+		hits     int
+		attacked map[string]int
+		defended map[string]int
+	}{
+		{OolCost, OolCost, map[string]int{"inf": 1, "tan": 1, "bom": 1}, 1, map[string]int{"tan": 1, "bom": 1}, map[string]int{"tan": 1, "bom": 1}},
+		// The defending bomber is the weakest unit, the attacking one the
+		// strongest
+		{OolHitValue, OolHitValue, map[string]int{"inf": 1, "tan": 1, "bom": 1}, 1, map[string]int{"tan": 1, "bom": 1}, map[string]int{"inf": 1, "tan": 1}},
+		{OolCost, OolHitValue, map[string]int{"inf": 1, "tan": 1, "bom": 1}, 2, map[string]int{"bom": 1}, map[string]int{"tan": 1}},
+		// Per IPC the fighter is the weakest attacker
+		{OolValuePerIpc, OolValuePerIpc, map[string]int{"inf": 1, "art": 1, "fig": 1}, 1, map[string]int{"inf": 1, "art": 1}, map[string]int{"inf": 1, "art": 1}},
+	}
+
+	for _, tt := range values {
+		s := NewSimulator(WithOolProfiles(tt.attacker, tt.defender))
+		ool := s.customizeOol(tt.units, tt.units)
+
+		attacked, defended := copyFormation(tt.units), copyFormation(tt.units)
+		s.takeCasualties(attacked, tt.hits, ool.attacker)
+		s.takeCasualties(defended, tt.hits, ool.defender)
+
+		if !reflect.DeepEqual(tt.attacked, attacked) || !reflect.DeepEqual(tt.defended, defended) {
+			t.Errorf("%v and %v profiles did not take casualties properly\nexpected: %v %v\nactual: %v %v", tt.attacker, tt.defender, tt.attacked, tt.defended, attacked, defended)
+		}
+		last := []string{"tra", "aaa", "raaa"}
+		if !reflect.DeepEqual(ool.attacker[len(ool.attacker)-3:], last) || !reflect.DeepEqual(ool.defender[len(ool.defender)-3:], last) {
+			t.Errorf("transports and AAA were not taken last\nattacker: %v\ndefender: %v", ool.attacker, ool.defender)
+		}
+	}
 }
@@ -5,49 +5,48 @@ import (
 	"strings"
 )
 
-var (
-	landTroops     []string
-	bombardShips   []string
-	capitalShips   []string
-	aircraft       []string
-	subs           []string
-	surfaceShips   []string
-	ships          []string
-	baseOol        []string
-	noSubOol       []string
-	multiRollUnits []string
+// OolProfile is a strategy for the order in which a side takes its losses
+type OolProfile string
+
+const (
+	// OolCost takes the cheapest units first
+	OolCost OolProfile = "cost"
+
+	// OolHitValue takes the weakest units first, by their attack when
+	// attacking and their defence when defending
+	OolHitValue OolProfile = "hitValue"
+
+	// OolValuePerIpc takes the units with the lowest hit value per IPC of cost
+	// first
+	OolValuePerIpc OolProfile = "valuePerIpc"
 )
 
-func resetOol() {
-	landTroops = []string{}
-	bombardShips = []string{}
-	capitalShips = []string{}
-	aircraft = []string{}
-	subs = []string{}
-	surfaceShips = []string{}
-	ships = []string{}
-	baseOol = []string{}
-	noSubOol = []string{}
-	multiRollUnits = []string{}
+// resetOol empties all the unit slices of the simulator
+func (s *Simulator) resetOol() {
+	s.landTroops = []string{}
+	s.bombardShips = []string{}
+	s.capitalShips = []string{}
+	s.aircraft = []string{}
+	s.subs = []string{}
+	s.surfaceShips = []string{}
+	s.ships = []string{}
+	s.baseOol = []string{}
+	s.multiRollUnits = []string{}
+	s.defenselessTransports = []string{}
 }
 
 // setupOol creates all the unit slices that we will use within the engine.
-func setupOol() {
+func (s *Simulator) setupOol() {
 
-	resetOol()
+	s.resetOol()
 
 	var hasAAA bool
 
-	switch oolProfile {
-	case "cost":
-		sort.Sort(ByCost{activeUnits})
-	case "hitValue":
-		sort.Sort(ByCost{activeUnits})
-	}
+	sort.Sort(ByCost{s.units})
 
 	// Range over all the active unit for the specific game that is being
 	// played and add them to their appropriate unit slices
-	for _, p := range activeUnits {
+	for _, p := range s.units {
 		// If the unit is an AAA we skip entirely. It needs to be added to the
 		// baseOol last because of the special rules regarding when it can be
 		// taken.
@@ -55,52 +54,128 @@ func setupOol() {
 			hasAAA = true
 			continue
 		}
+		// Defenseless transports are also added last, as they may only be
+		// taken once every other unit has been
+		if p.IsTransport && p.Defend == 0 {
+			s.defenselessTransports = append(s.defenselessTransports, p.Alias)
+			continue
+		}
 		if p.CanTakeTerritory {
-			landTroops = append(landTroops, p.Alias)
+			s.landTroops = append(s.landTroops, p.Alias)
 		}
 		if p.CanBombard {
-			bombardShips = append(bombardShips, p.Alias)
+			s.bombardShips = append(s.bombardShips, p.Alias)
 		}
 		if p.CapitalShip {
-			capitalShips = append(capitalShips, p.Alias)
+			s.capitalShips = append(s.capitalShips, p.Alias)
 		}
 		if p.IsAircraft {
-			aircraft = append(aircraft, p.Alias)
+			s.aircraft = append(s.aircraft, p.Alias)
 		}
 		if p.IsShip && !p.IsSub {
-			surfaceShips = append(surfaceShips, p.Alias)
+			s.surfaceShips = append(s.surfaceShips, p.Alias)
 		}
 		if p.IsShip {
-			ships = append(ships, p.Alias)
+			s.ships = append(s.ships, p.Alias)
 		}
 		if p.IsSub {
-			subs = append(subs, p.Alias)
-		}
-		if !p.IsSub {
-			noSubOol = append(noSubOol, p.Alias)
+			s.subs = append(s.subs, p.Alias)
 		}
 		if p.MultiRoll > 0 {
-			multiRollUnits = append(multiRollUnits, p.Alias)
+			s.multiRollUnits = append(s.multiRollUnits, p.Alias)
 		}
-		baseOol = append(baseOol, p.Alias)
+		s.baseOol = append(s.baseOol, p.Alias)
 	}
 
+	s.surfaceShips = append(s.surfaceShips, s.defenselessTransports...)
+	s.ships = append(s.ships, s.defenselessTransports...)
+	s.baseOol = append(s.baseOol, s.defenselessTransports...)
+
 	// Every OOL that we create must add the "aaa" last. because AAA is a
 	// special unit that must always be taken last.
 	if hasAAA {
-		baseOol = append(baseOol, "aaa", "raaa", "aag")
+		s.baseOol = append(s.baseOol, "aaa", "raaa", "aag")
 	}
 
+	s.attackerOol = s.profileOol(s.attackerOolProfile, "attack")
+	s.defenderOol = s.profileOol(s.defenderOolProfile, "defend")
 }
 
-// customizeOol takes the system's baseOol and customizes it for the particular
-// units that have been passed in. The primary function in the real world is
-// that it will add all reserved attackers and defenders to the appropriate
-// spot in the ool, and add AAA to the end of the ool since, AAA must be taken
-// last
-func customizeOol(attackers, defenders map[string]int) []string {
-	ool := make([]string, len(baseOol))
-	copy(ool, baseOol)
+// profileOol returns the order of loss of a side taking its losses according
+// to the profile, mode being "attack" or "defend". Like the baseOol,
+// defenseless transports and AAA are always taken last.
+func (s *Simulator) profileOol(profile OolProfile, mode string) []string {
+	units := make(Units, len(s.units))
+	copy(units, s.units)
+
+	switch {
+	case profile == OolHitValue && mode == "attack":
+		sort.Stable(ByAttackingPower{units})
+	case profile == OolHitValue:
+		sort.Stable(ByDefendingPower{units})
+	case profile == OolValuePerIpc && mode == "attack":
+		sort.Stable(ByAttackingValue{units})
+	case profile == OolValuePerIpc:
+		sort.Stable(ByDefendingValue{units})
+	default:
+		return s.baseOol
+	}
+
+	ool := make([]string, 0, len(s.baseOol))
+	for _, p := range units {
+		if p.Alias == "aaa" || p.Alias == "raaa" || p.Alias == "aag" || sliceHasValue(s.defenselessTransports, p.Alias) {
+			continue
+		}
+		ool = append(ool, p.Alias)
+	}
+
+	// The tail of the baseOol holds the units always taken last
+	return append(ool, s.baseOol[len(ool):]...)
+}
+
+// conflictOol is the order of loss of each side of a conflict. The noSub
+// orders of loss are those used against aircraft unable to hit submarines.
+type conflictOol struct {
+	attacker      []string
+	defender      []string
+	attackerNoSub []string
+	defenderNoSub []string
+}
+
+// customizeOol customizes the order of loss of each side for the particular
+// units that have been passed in.
+func (s *Simulator) customizeOol(attackers, defenders map[string]int) *conflictOol {
+	attacker := s.customizeSideOol(s.attackerOol, attackers, attackers, defenders)
+	defender := s.customizeSideOol(s.defenderOol, defenders, attackers, defenders)
+
+	return &conflictOol{
+		attacker:      attacker,
+		defender:      defender,
+		attackerNoSub: s.withoutSubs(attacker),
+		defenderNoSub: s.withoutSubs(defender),
+	}
+}
+
+// customizeSideOol takes the order of loss of a side and customizes it for the
+// particular units that have been passed in. The primary function in the real
+// world is that it will add all reserved attackers and defenders to the
+// appropriate spot in the ool, and add AAA to the end of the ool since, AAA
+// must be taken last. The side is the formation of the side the ool is for.
+func (s *Simulator) customizeSideOol(base []string, side, attackers, defenders map[string]int) []string {
+	ool := make([]string, 0, len(base))
+	for _, alias := range base {
+		if alias == "aaa" || alias == "raaa" || alias == "aag" || sliceHasValue(s.defenselessTransports, alias) {
+			continue
+		}
+		ool = append(ool, alias)
+	}
+
+	// A carrier is worth more than its cost while it carries aircraft, as they
+	// are lost along with it. So it is taken after every other ship by the
+	// side whose aircraft it carries.
+	if hasCarrierBasedAircraft(side) {
+		ool = s.takeCarriersLast(ool)
+	}
 
 	// We need to see all reserved attackers and add them to the end of the ool
 	for alias := range attackers {
@@ -117,15 +192,69 @@ func customizeOol(attackers, defenders map[string]int) []string {
 		}
 	}
 
+	// Defenseless transports are taken after every unit, reserved or not
+	ool = append(ool, s.defenselessTransports...)
+
 	// AAA Is always the last thing taken in any conflict
-	if activeUnits.HasUnit("aaa") {
+	if s.units.HasUnit("aaa") {
 		ool = append(ool, "aaa")
 	}
-	if activeUnits.HasUnit("raaa") {
+	if s.units.HasUnit("raaa") {
 		ool = append(ool, "raaa")
 	}
-	if activeUnits.HasUnit("aag") {
+	if s.units.HasUnit("aag") {
 		ool = append(ool, "aag")
 	}
 	return ool
 }
+
+// takeCarriersLast moves the carriers of the ool to just after the last ship
+func (s *Simulator) takeCarriersLast(ool []string) []string {
+	var carriers, others []string
+	lastShip := -1
+	for _, alias := range ool {
+		unit := s.units.Find(alias)
+		if unit.CarrierCapacity > 0 {
+			carriers = append(carriers, alias)
+			continue
+		}
+
+		others = append(others, alias)
+		if unit.IsShip {
+			lastShip = len(others) - 1
+		}
+	}
+
+	if len(carriers) == 0 {
+		return ool
+	}
+
+	reordered := make([]string, 0, len(ool))
+	reordered = append(reordered, others[:lastShip+1]...)
+	reordered = append(reordered, carriers...)
+	return append(reordered, others[lastShip+1:]...)
+}
+
+// hasCarrierBasedAircraft returns whether any aircraft of the formation are
+// based on a carrier
+func hasCarrierBasedAircraft(f map[string]int) bool {
+	for alias := range f {
+		if strings.HasPrefix(alias, "*") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// withoutSubs returns the ool without any of the submarines
+func (s *Simulator) withoutSubs(ool []string) []string {
+	noSub := make([]string, 0, len(ool))
+	for _, alias := range ool {
+		if !sliceHasUnit(s.subs, alias) {
+			noSub = append(noSub, alias)
+		}
+	}
+
+	return noSub
+}
@@ -0,0 +1,54 @@
+package oddsengine
+
+// RetreatPolicy describes when the attacker retreats from a conflict. Rules
+// left at their zero value are ignored, the attacker retreats at the end of the
+// first round where any of the set rules apply. The zero RetreatPolicy fights
+// every conflict to the end.
+type RetreatPolicy struct {
+	// AfterRounds retreats once this many rounds have been fought
+	AfterRounds int `json:"afterRounds"`
+
+	// MinUnits retreats once the attacker has fewer units than this remaining
+	MinUnits int `json:"minUnits"`
+
+	// MinIpcValue retreats once the attacking units remaining are worth fewer
+	// IPCs than this
+	MinIpcValue int `json:"minIpcValue"`
+
+	// WhenOutnumbered retreats once the defender has more units remaining than
+	// the attacker
+	WhenOutnumbered bool `json:"whenOutnumbered"`
+}
+
+// shouldRetreat returns whether the attacker retreats from the conflict after
+// the passed in number of rounds have been fought.
+func (s *Simulator) shouldRetreat(attackers, defenders map[string]int, rounds int) bool {
+	r := s.retreat
+	if rounds == 0 {
+		return false
+	}
+
+	if r.AfterRounds > 0 && rounds >= r.AfterRounds {
+		return true
+	}
+
+	if r.MinUnits > 0 && getTotalNumUnits(attackers) < r.MinUnits {
+		return true
+	}
+
+	if r.MinIpcValue > 0 && s.ipcValue(attackers) < r.MinIpcValue {
+		return true
+	}
+
+	return r.WhenOutnumbered && getTotalNumUnits(defenders) > getTotalNumUnits(attackers)
+}
+
+// ipcValue returns the total cost of all the units within a formation,
+// including the cargo of any transports
+func (s *Simulator) ipcValue(f map[string]int) (value int) {
+	for alias, n := range f {
+		value += s.lossValue(s.units.Find(realAlias(alias))) * n
+	}
+
+	return value
+}
@@ -0,0 +1,89 @@
+package oddsengine
+
+import (
+	"math"
+	"testing"
+)
+
+func TestShouldRetreat(t *testing.T) {
+	values := []struct {
+		policy    RetreatPolicy
+		attackers map[string]int
+		defenders map[string]int
+		rounds    int
+		retreat   bool
+	}{
+		{RetreatPolicy{}, map[string]int{"inf": 1}, map[string]int{"inf": 5}, 3, false},
+		{RetreatPolicy{AfterRounds: 2}, map[string]int{"inf": 3}, map[string]int{"inf": 1}, 1, false},
+		{RetreatPolicy{AfterRounds: 2}, map[string]int{"inf": 3}, map[string]int{"inf": 1}, 2, true},
+		{RetreatPolicy{MinUnits: 3}, map[string]int{"inf": 1, "+tan": 1}, map[string]int{"inf": 1}, 1, true},
+		{RetreatPolicy{MinUnits: 3}, map[string]int{"inf": 2, "+tan": 1}, map[string]int{"inf": 1}, 1, false},
+		{RetreatPolicy{MinIpcValue: 10}, map[string]int{"inf": 2, "+tan": 1}, map[string]int{"inf": 1}, 1, false},
+		{RetreatPolicy{MinIpcValue: 10}, map[string]int{"inf": 3}, map[string]int{"inf": 1}, 1, true},
+		{RetreatPolicy{WhenOutnumbered: true}, map[string]int{"tan": 2}, map[string]int{"inf": 3}, 1, true},
+		{RetreatPolicy{WhenOutnumbered: true}, map[string]int{"tan": 3}, map[string]int{"inf": 3}, 1, false},
+		// The attacker never retreats before the first round is fought
+		{RetreatPolicy{WhenOutnumbered: true}, map[string]int{"tan": 2}, map[string]int{"inf": 3}, 0, false},
+	}
+
+	for _, tt := range values {
+		s := NewSimulator(WithRetreat(tt.policy))
+		if s.shouldRetreat(tt.attackers, tt.defenders, tt.rounds) != tt.retreat {
+			t.Errorf("retreat was decided incorrectly\npolicy: %+v\nattackers: %v\ndefenders: %v\nrounds: %v", tt.policy, tt.attackers, tt.defenders, tt.rounds)
+		}
+	}
+}
+
+func TestRetreatAfterRounds(t *testing.T) {
+	s := NewSimulator(WithRetreat(RetreatPolicy{AfterRounds: 1}), WithIterations(1000), WithSeed(1))
+	summary, err := s.GetSummary(map[string]int{"inf": 6, "art": 2, "tan": 2}, map[string]int{"inf": 8, "tan": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	total := summary.AttackerWinPercentage + summary.DefenderWinPercentage + summary.DrawPercentage + summary.AttackerRetreatPercentage
+	if summary.AverageRounds != 1 || summary.AttackerRetreatPercentage < 90 || math.Abs(total-100) > 0.01 {
+		t.Errorf("attacker did not retreat after the first round\n%+v", summary)
+	}
+
+	var retreats int
+	for _, r := range summary.FirstRoundResults {
+		retreats += r.AttackerRetreat
+	}
+	if float64(retreats) != summary.AttackerRetreatPercentage*10 {
+		t.Errorf("first round results did not record the retreats\n%+v", summary.FirstRoundResults)
+	}
+
+	if _, err := s.GetExactSummary(map[string]int{"inf": 1}, map[string]int{"inf": 1}); err == nil {
+		t.Errorf("retreating after a number of rounds should not be solved exactly")
+	}
+}
+
+func TestExactRetreatMatchesSimulation(t *testing.T) {
+	values := []RetreatPolicy{
+		{MinUnits: 4},
+		{MinIpcValue: 20},
+		{WhenOutnumbered: true},
+	}
+	attackers := map[string]int{"inf": 4, "art": 2, "tan": 2}
+	defenders := map[string]int{"inf": 5, "tan": 1}
+
+	for _, policy := range values {
+		exact, err := NewSimulator(WithRetreat(policy)).GetExactSummary(attackers, defenders)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		simulated, err := NewSimulator(WithRetreat(policy), WithIterations(20000), WithSeed(1)).GetSummary(attackers, defenders)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if exact.AttackerRetreatPercentage == 0 ||
+			math.Abs(exact.AttackerRetreatPercentage-simulated.AttackerRetreatPercentage) > 1.5 ||
+			math.Abs(exact.AttackerWinPercentage-simulated.AttackerWinPercentage) > 1.5 ||
+			math.Abs(exact.AverageRounds-simulated.AverageRounds) > 0.1 {
+			t.Errorf("exact retreat odds stray from the simulation\npolicy: %+v\nexact: %+v\nsimulated: %+v", policy, *exact, *simulated)
+		}
+	}
+}
@@ -61,11 +61,12 @@ func (r RollMap) Reduce(hitValue, num int) (newMap RollMap) {
 }
 
 // RemoveUnits reduces the roll map by the passed in slice of units for the given.
-// mode. Uses the formation to determine what numbers to remove
-func (r RollMap) RemoveUnits(f map[string]int, units []string, mode string) {
+// mode. Uses the formation to determine what numbers to remove, looking the
+// units up within the passed in unit table p
+func (r RollMap) RemoveUnits(p Units, f map[string]int, units []string, mode string) {
 	for _, alias := range units {
 		if hasUnit(f, alias) {
-			unit := activeUnits.Find(realAlias(alias))
+			unit := p.Find(realAlias(alias))
 
 			totalNumberUnits := numAllUnitsInFormation(f, alias)
 			var unitsAtPlusOne int
@@ -0,0 +1,180 @@
+package oddsengine
+
+// RoundState is the state of a conflict at the end of one of its rounds
+type RoundState struct {
+	// AttackerUnits The number of attacking units left
+	AttackerUnits int
+
+	// DefenderUnits The number of defending units left
+	DefenderUnits int
+
+	// AttackerIpcValue The IPC value of the attacking units left
+	AttackerIpcValue int
+
+	// DefenderIpcValue The IPC value of the defending units left
+	DefenderIpcValue int
+
+	// Continuing Whether the conflict was still going after the round. It
+	// may yet end before the next round is fought, by the attacker retreating
+	// or the maximum number of rounds being reached.
+	Continuing bool
+}
+
+// RoundSummary is how the conflicts of a Summary stood at the end of a round.
+// The averages and outcome percentages only cover the conflicts which fought
+// the round.
+type RoundSummary struct {
+	// Round The round, starting at 1
+	Round int `json:"round"`
+
+	// ReachedPercentage The percentage of conflicts which fought the round
+	ReachedPercentage float64 `json:"reachedPercentage"`
+
+	// ContinuingPercentage The percentage of conflicts still going after the
+	// round, whether or not they fought the next round
+	ContinuingPercentage float64 `json:"continuingPercentage"`
+
+	// AttackerAvgUnits The number of attacking units left on average
+	AttackerAvgUnits float64 `json:"attackerAvgUnits"`
+
+	// DefenderAvgUnits The number of defending units left on average
+	DefenderAvgUnits float64 `json:"defenderAvgUnits"`
+
+	// AttackerAvgIpcValue The IPC value of the attacking units left on average
+	AttackerAvgIpcValue float64 `json:"attackerAvgIpcValue"`
+
+	// DefenderAvgIpcValue The IPC value of the defending units left on average
+	DefenderAvgIpcValue float64 `json:"defenderAvgIpcValue"`
+
+	// AttackerWinPercentage The percentage of the conflicts reaching the
+	// round that the attacker won
+	AttackerWinPercentage float64 `json:"attackerWinPercentage"`
+
+	// DefenderWinPercentage The percentage of the conflicts reaching the
+	// round that the defender won
+	DefenderWinPercentage float64 `json:"defenderWinPercentage"`
+
+	// DrawPercentage The percentage of the conflicts reaching the round that
+	// were a draw
+	DrawPercentage float64 `json:"drawPercentage"`
+
+	// AttackerRetreatPercentage The percentage of the conflicts reaching the
+	// round that the attacker retreated from
+	AttackerRetreatPercentage float64 `json:"attackerRetreatPercentage"`
+
+	// UnresolvedPercentage The percentage of the conflicts reaching the round
+	// that were left unresolved
+	UnresolvedPercentage float64 `json:"unresolvedPercentage"`
+}
+
+// roundTotals are the running totals of the conflicts reaching a round, a
+// RoundSummary is built from
+type roundTotals struct {
+	reached          float64
+	continuing       float64
+	attackerUnits    float64
+	defenderUnits    float64
+	attackerIpcValue float64
+	defenderIpcValue float64
+	attackerWins     float64
+	defenderWins     float64
+	draws            float64
+	attackerRetreats float64
+	unresolved       float64
+}
+
+// roundState returns the state of the conflict between the attackers and
+// defenders
+func (s *Simulator) roundState(attackers, defenders map[string]int) RoundState {
+	return RoundState{
+		AttackerUnits:    getTotalNumUnits(attackers),
+		DefenderUnits:    getTotalNumUnits(defenders),
+		AttackerIpcValue: s.ipcValue(attackers),
+		DefenderIpcValue: s.ipcValue(defenders),
+		Continuing:       !s.isResolved(attackers, defenders),
+	}
+}
+
+// addRounds records the rounds of a profile into the totals, returning them
+// grown to cover every round of the profile
+func addRounds(totals []roundTotals, profile *ConflictProfile) []roundTotals {
+	for len(totals) < len(profile.RoundStates) {
+		totals = append(totals, roundTotals{})
+	}
+
+	for i, state := range profile.RoundStates {
+		t := &totals[i]
+		t.reached++
+		if state.Continuing {
+			t.continuing++
+		}
+		t.attackerUnits += float64(state.AttackerUnits)
+		t.defenderUnits += float64(state.DefenderUnits)
+		t.attackerIpcValue += float64(state.AttackerIpcValue)
+		t.defenderIpcValue += float64(state.DefenderIpcValue)
+
+		switch profile.Outcome {
+		case AttackerWin:
+			t.attackerWins++
+		case DefenderWin:
+			t.defenderWins++
+		case Draw:
+			t.draws++
+		case AttackerRetreat:
+			t.attackerRetreats++
+		case Unresolved:
+			t.unresolved++
+		}
+	}
+
+	return totals
+}
+
+// mergeRounds adds the totals of b into a, returning them grown to cover every
+// round of b
+func mergeRounds(a, b []roundTotals) []roundTotals {
+	for len(a) < len(b) {
+		a = append(a, roundTotals{})
+	}
+
+	for i, t := range b {
+		a[i].reached += t.reached
+		a[i].continuing += t.continuing
+		a[i].attackerUnits += t.attackerUnits
+		a[i].defenderUnits += t.defenderUnits
+		a[i].attackerIpcValue += t.attackerIpcValue
+		a[i].defenderIpcValue += t.defenderIpcValue
+		a[i].attackerWins += t.attackerWins
+		a[i].defenderWins += t.defenderWins
+		a[i].draws += t.draws
+		a[i].attackerRetreats += t.attackerRetreats
+		a[i].unresolved += t.unresolved
+	}
+
+	return a
+}
+
+// roundSummaries creates the RoundSummary of every round out of the totals of
+// n conflicts
+func roundSummaries(totals []roundTotals, n float64) []RoundSummary {
+	rounds := make([]RoundSummary, 0, len(totals))
+	for i, t := range totals {
+		r := RoundSummary{
+			Round:                     i + 1,
+			ReachedPercentage:         round(t.reached/n*100, 2),
+			ContinuingPercentage:      round(t.continuing/n*100, 2),
+			AttackerAvgUnits:          round(t.attackerUnits/t.reached, 2),
+			DefenderAvgUnits:          round(t.defenderUnits/t.reached, 2),
+			AttackerAvgIpcValue:       round(t.attackerIpcValue/t.reached, 2),
+			DefenderAvgIpcValue:       round(t.defenderIpcValue/t.reached, 2),
+			AttackerWinPercentage:     round(t.attackerWins/t.reached*100, 2),
+			DefenderWinPercentage:     round(t.defenderWins/t.reached*100, 2),
+			DrawPercentage:            round(t.draws/t.reached*100, 2),
+			AttackerRetreatPercentage: round(t.attackerRetreats/t.reached*100, 2),
+			UnresolvedPercentage:      round(t.unresolved/t.reached*100, 2),
+		}
+		rounds = append(rounds, r)
+	}
+
+	return rounds
+}
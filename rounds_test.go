@@ -0,0 +1,96 @@
+package oddsengine
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRoundStates(t *testing.T) {
+	s := NewSimulator(WithDice(&countingDice{}))
+
+	// Every die hits, the attacker is destroyed in the first round
+	attackers, defenders := map[string]int{"inf": 2}, map[string]int{"inf": 3, "art": 1}
+	p := s.resolveConflict(attackers, defenders, s.customizeOol(attackers, defenders))
+
+	expected := []RoundState{{AttackerUnits: 0, DefenderUnits: 2, AttackerIpcValue: 0, DefenderIpcValue: 7}}
+	if !reflect.DeepEqual(p.RoundStates, expected) {
+		t.Errorf("round states were not recorded correctly\nexpected: %+v\nactual: %+v", expected, p.RoundStates)
+	}
+}
+
+func TestRoundSummaries(t *testing.T) {
+	profiles := []ConflictProfile{
+		{Rounds: 2, AttackerHits: []int{1, 2}, DefenderHits: []int{1, 1}, Outcome: AttackerWin, RoundStates: []RoundState{{3, 2, 9, 6, true}, {2, 0, 6, 0, false}}},
+		{Rounds: 1, AttackerHits: []int{1}, DefenderHits: []int{3}, Outcome: DefenderWin, RoundStates: []RoundState{{1, 3, 3, 9, false}}},
+		// The attacker retreats before the fourth round, the conflict still
+		// going after the third
+		{Rounds: 3, AttackerHits: []int{1, 1, 0}, DefenderHits: []int{2, 0, 0}, Outcome: AttackerRetreat, RoundStates: []RoundState{{2, 2, 6, 6, true}, {2, 1, 6, 3, true}, {2, 1, 6, 3, true}}},
+		// A conflict that is over before the first round is fought
+		{Rounds: 0, Outcome: AttackerWin},
+	}
+
+	expected := []RoundSummary{
+		{
+			Round:                     1,
+			ReachedPercentage:         75,
+			ContinuingPercentage:      50,
+			AttackerAvgUnits:          2,
+			DefenderAvgUnits:          2.33,
+			AttackerAvgIpcValue:       6,
+			DefenderAvgIpcValue:       7,
+			AttackerWinPercentage:     33.33,
+			DefenderWinPercentage:     33.33,
+			AttackerRetreatPercentage: 33.33,
+		},
+		{
+			Round:                     2,
+			ReachedPercentage:         50,
+			ContinuingPercentage:      25,
+			AttackerAvgUnits:          2,
+			DefenderAvgUnits:          0.5,
+			AttackerAvgIpcValue:       6,
+			DefenderAvgIpcValue:       1.5,
+			AttackerWinPercentage:     50,
+			AttackerRetreatPercentage: 50,
+		},
+		{
+			Round:                     3,
+			ReachedPercentage:         25,
+			ContinuingPercentage:      25,
+			AttackerAvgUnits:          2,
+			DefenderAvgUnits:          1,
+			AttackerAvgIpcValue:       6,
+			DefenderAvgIpcValue:       3,
+			AttackerRetreatPercentage: 100,
+		},
+	}
+
+	if actual := generateSummary(profiles).Rounds; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("round summaries were not generated correctly\nexpected: %+v\nactual: %+v", expected, actual)
+	}
+
+	// Accumulators holding fewer rounds are merged all the same
+	first, second := newSummaryAccumulator(), newSummaryAccumulator()
+	first.add(&profiles[1])
+	first.add(&profiles[3])
+	second.add(&profiles[2])
+	second.add(&profiles[0])
+	first.merge(second)
+
+	if actual := first.summary().Rounds; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("merged round summaries did not match\nexpected: %+v\nactual: %+v", expected, actual)
+	}
+}
+
+func TestContinuingPercentage(t *testing.T) {
+	// Conflicts still going after the last round allowed are left unresolved,
+	// without fighting the next round
+	summary, err := NewSimulator(WithIterations(2000), WithSeed(1), WithMaxRounds(1)).GetSummary(map[string]int{"inf": 3, "tan": 1}, map[string]int{"inf": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(summary.Rounds) != 1 || summary.UnresolvedPercentage == 0 || summary.Rounds[0].ContinuingPercentage != summary.UnresolvedPercentage {
+		t.Errorf("conflicts left unresolved were not counted as continuing\n%+v", summary.Rounds)
+	}
+}
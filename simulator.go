@@ -0,0 +1,580 @@
+package oddsengine
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// streamSize is the number of conflicts resolved from a single random stream.
+// Conflicts are split into streams of this size regardless of how many run in
+// parallel, so a seeded simulation always rolls the same dice for the same
+// conflict.
+const streamSize = 100
+
+// precisionIterations is the number of conflicts resolved before the precision
+// of a simulation is first checked.
+const precisionIterations = 1000
+
+// Simulator is a self contained odds engine. Each Simulator owns its game,
+// unit table, order of loss and random source so many differently configured
+// simulators can run side by side within one process.
+type Simulator struct {
+	// game is the game currently being run by the simulator
+	game string
+
+	// units are the units available in the current game version
+	units Units
+
+	// iterations is the number of times we will run the sim and generate a
+	// ConflictProfile for the Summary. Default is 1000
+	iterations int
+
+	// keepLandUnits is the number of attacking land units kept alive for as
+	// long as any other attacking unit is able to take the hits
+	keepLandUnits int
+
+	// attackerOolProfile and defenderOolProfile are the strategies each side
+	// uses for taking losses. Default is OolCost
+	attackerOolProfile OolProfile
+	defenderOolProfile OolProfile
+
+	// attackerSelector and defenderSelector choose the casualties of each
+	// side, the order of loss is followed when nil
+	attackerSelector CasualtySelector
+	defenderSelector CasualtySelector
+
+	// customOol is a user supplied order of loss which replaces the generated
+	// baseOol, and the order of loss of both sides
+	customOol []string
+
+	landTroops     []string
+	bombardShips   []string
+	capitalShips   []string
+	aircraft       []string
+	subs           []string
+	surfaceShips   []string
+	ships          []string
+	baseOol        []string
+	multiRollUnits []string
+
+	// attackerOol and defenderOol are the orders of loss of each side, built
+	// from their profiles
+	attackerOol []string
+	defenderOol []string
+
+	// defenselessTransports are the transports unable to defend themselves,
+	// taken only once every other unit has been
+	defenselessTransports []string
+
+	// assault is the amphibious assault every conflict is part of, only set
+	// on the copy of a simulator resolving one
+	assault *amphibiousAssault
+
+	// cargoValue is the IPC value of the cargo lost with every transport
+	cargoValue int
+
+	// territoryValue is the IPC income of the territory being attacked
+	territoryValue int
+
+	// retreat is when the attacker retreats from a conflict
+	retreat RetreatPolicy
+
+	// sampleTraces is the number of conflicts of each simulation traced and
+	// attached to its Summary
+	sampleTraces int
+
+	// trace records the conflict being resolved, only set on the copy of a
+	// simulator tracing one
+	trace *Trace
+
+	// observer is notified of every step of the conflicts resolved
+	observer Observer
+
+	// observing notifies the observer of the conflict being resolved, only set
+	// on the copy of a simulator resolving one
+	observing *observation
+
+	// maxRounds is the most rounds fought before a conflict is left
+	// unresolved, 0 fights until the conflict is resolved
+	maxRounds int
+
+	// attackerSubmerge and defenderSubmerge are when each side's submarines
+	// submerge
+	attackerSubmerge SubmergePolicy
+	defenderSubmerge SubmergePolicy
+
+	// lowLuck sums the hit values of the units rolling together, scoring a
+	// hit for every full die and only rolling the remainder
+	lowLuck bool
+
+	// lowLuckSpecial applies Low Luck to AAA, kamikaze and multi roll units
+	// too, otherwise they roll their dice as normal
+	lowLuckSpecial bool
+
+	// seed is the seed every random stream of a simulation is derived from.
+	// Only used when seeded is set, otherwise a new seed is picked for each
+	// simulation
+	seed   int64
+	seeded bool
+
+	// rng is the random source used for every die rolled by the simulator,
+	// unless dice are set
+	rng *rand.Rand
+
+	// dice replaces rng as the source of every die rolled by the simulator
+	dice Dice
+
+	// workers is the number of conflicts resolved in parallel, 0 uses one
+	// worker per CPU available to the process
+	workers int
+
+	// margin is the margin of error, in percentage points, the win
+	// percentages must reach before a simulation stops. When set, maxIterations
+	// replaces iterations as the most conflicts that will be resolved
+	margin        float64
+	maxIterations int
+
+	// progress is called as the streams of a simulation are resolved, nil when
+	// progress is not reported
+	progress func(Progress)
+}
+
+// Progress reports how far a running simulation has got, along with the
+// running win percentages of the conflicts resolved so far. Bombing raids only
+// report the iterations.
+type Progress struct {
+	// CompletedIterations The number of conflicts resolved so far
+	CompletedIterations int `json:"completedIterations"`
+
+	// TotalIterations The number of conflicts the simulation will resolve
+	TotalIterations int `json:"totalIterations"`
+
+	// AttackerWinPercentage The percentage of resolved conflicts that the
+	// attacker won
+	AttackerWinPercentage float64 `json:"attackerWinPercentage"`
+
+	// DefenderWinPercentage The percentage of resolved conflicts that the
+	// defender won
+	DefenderWinPercentage float64 `json:"defenderWinPercentage"`
+
+	// DrawPercentage The percentage of resolved conflicts that were a draw
+	DrawPercentage float64 `json:"drawPercentage"`
+}
+
+// Option configures a Simulator when passed to NewSimulator
+type Option func(*Simulator)
+
+// WithGame sets the game the Simulator will run. Default is "1940"
+func WithGame(g string) Option {
+	return func(s *Simulator) {
+		s.game = g
+	}
+}
+
+// WithIterations sets the number of times the simulation will be ran.
+// Default is 1000. A number of 0 or less is rejected when the simulation is
+// run.
+func WithIterations(i int) Option {
+	return func(s *Simulator) {
+		s.iterations = i
+	}
+}
+
+// WithMustTakeTerritory keeps an attacking land unit alive to take the
+// territory, the same as WithKeepLandUnits(1). Passing false keeps none.
+func WithMustTakeTerritory(a bool) Option {
+	return func(s *Simulator) {
+		s.keepLandUnits = mustTakeTerritory(a, s.keepLandUnits)
+	}
+}
+
+// WithKeepLandUnits keeps n attacking land units alive for as long as any
+// other attacking unit is able to take the hits, so the territory can be
+// taken. The cheapest land units are the ones kept.
+func WithKeepLandUnits(n int) Option {
+	return func(s *Simulator) {
+		s.keepLandUnits = n
+	}
+}
+
+// WithBaseOol allows a custom baseOol to be used for every conflict
+func WithBaseOol(ool []string) Option {
+	return func(s *Simulator) {
+		s.customOol = ool
+	}
+}
+
+// WithOolProfiles sets the strategy each side uses to take its losses. Both
+// sides take the cheapest units first by default. A custom baseOol set by
+// WithBaseOol takes precedence.
+func WithOolProfiles(attacker, defender OolProfile) Option {
+	return func(s *Simulator) {
+		s.attackerOolProfile = attacker
+		s.defenderOolProfile = defender
+	}
+}
+
+// WithCasualtySelectors sets how each side chooses its casualties. By default
+// both sides follow their order of loss, like OolSelector.
+func WithCasualtySelectors(attacker, defender CasualtySelector) Option {
+	return func(s *Simulator) {
+		s.attackerSelector = attacker
+		s.defenderSelector = defender
+	}
+}
+
+// WithRetreat sets when the attacker retreats from the conflict. By default
+// every conflict is fought until one side is destroyed.
+func WithRetreat(r RetreatPolicy) Option {
+	return func(s *Simulator) {
+		s.retreat = r
+	}
+}
+
+// WithMaxRounds stops each conflict once n rounds have been fought, recording
+// it as unresolved along with the units each side has remaining. A value of 1
+// resolves single round battles. Default is 0, fighting until the conflict is
+// resolved.
+func WithMaxRounds(n int) Option {
+	return func(s *Simulator) {
+		s.maxRounds = n
+	}
+}
+
+// WithAttackerSubmerge sets when the attacker's submarines submerge. By default
+// submarines never submerge.
+func WithAttackerSubmerge(p SubmergePolicy) Option {
+	return func(s *Simulator) {
+		s.attackerSubmerge = p
+	}
+}
+
+// WithDefenderSubmerge sets when the defender's submarines submerge. By default
+// submarines never submerge.
+func WithDefenderSubmerge(p SubmergePolicy) Option {
+	return func(s *Simulator) {
+		s.defenderSubmerge = p
+	}
+}
+
+// WithCargoValue sets the IPC value of the cargo carried by each transport,
+// which is counted along with the cost of every transport lost. Default is 0.
+func WithCargoValue(v int) Option {
+	return func(s *Simulator) {
+		s.cargoValue = v
+	}
+}
+
+// WithTerritoryValue sets the IPC income of the territory being attacked,
+// which is gained by the attacker whenever the territory is captured. Default
+// is 0.
+func WithTerritoryValue(v int) Option {
+	return func(s *Simulator) {
+		s.territoryValue = v
+	}
+}
+
+// WithLowLuck sets the Low Luck dice mode. The hit values of units rolling
+// together are summed, every full die (6, or 8 for the deluxe games) is a
+// guaranteed hit and only the remainder is rolled.
+func WithLowLuck(a bool) Option {
+	return func(s *Simulator) {
+		s.lowLuck = a
+	}
+}
+
+// WithLowLuckSpecialRolls sets whether AAA, kamikaze and multi roll units use
+// Low Luck as well, when the Low Luck dice mode is set. Default is false,
+// rolling them as normal.
+func WithLowLuckSpecialRolls(a bool) Option {
+	return func(s *Simulator) {
+		s.lowLuckSpecial = a
+	}
+}
+
+// WithSeed makes the simulations reproducible. The same seed, formations and
+// iteration count will always produce the same Summary.
+func WithSeed(seed int64) Option {
+	return func(s *Simulator) {
+		s.seed = seed
+		s.seeded = true
+	}
+}
+
+// WithPrecision runs each simulation until the 95% confidence interval of the
+// win percentages is within margin percentage points either side, or until
+// maxIterations conflicts have been resolved. The iteration count set by
+// WithIterations is ignored. A maxIterations of 0 or less is rejected when the
+// simulation is run.
+func WithPrecision(margin float64, maxIterations int) Option {
+	return func(s *Simulator) {
+		s.margin = margin
+		s.maxIterations = maxIterations
+	}
+}
+
+// WithDice rolls every die of the simulations with the passed in Dice rather
+// than the simulator's own random source. The seed set by WithSeed has no
+// effect on the rolls of the Dice.
+func WithDice(d Dice) Option {
+	return func(s *Simulator) {
+		s.dice = d
+	}
+}
+
+// WithSampleTraces traces the first n conflicts of every simulation, attaching
+// them to the Summary. Default is 0.
+func WithSampleTraces(n int) Option {
+	return func(s *Simulator) {
+		s.sampleTraces = n
+	}
+}
+
+// WithObserver notifies the Observer of every step of the conflicts resolved.
+// Default is nil, which observes nothing.
+func WithObserver(o Observer) Option {
+	return func(s *Simulator) {
+		s.observer = o
+	}
+}
+
+// WithWorkers sets the number of conflicts resolved in parallel. Default is one
+// per CPU available to the process.
+func WithWorkers(n int) Option {
+	return func(s *Simulator) {
+		s.workers = n
+	}
+}
+
+// WithProgress registers a callback reporting the progress of every simulation
+// ran by the Simulator. The callback is called from a single goroutine, once
+// each stream of conflicts has been resolved.
+func WithProgress(f func(Progress)) Option {
+	return func(s *Simulator) {
+		s.progress = f
+	}
+}
+
+// NewSimulator creates a Simulator configured by the passed in options.
+func NewSimulator(opts ...Option) *Simulator {
+	s := &Simulator{
+		game:               "1940",
+		iterations:         1000,
+		attackerOolProfile: OolCost,
+		defenderOolProfile: OolCost,
+		rng:                rand.New(rand.NewSource(time.Now().UTC().UnixNano())),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.setup()
+
+	return s
+}
+
+// setup loads the units for the simulator's game and builds all the unit
+// slices used within the engine.
+func (s *Simulator) setup() {
+	s.units = getUnitsForGame(s.game)
+	s.setupOol()
+
+	if s.customOol != nil {
+		s.baseOol = s.customOol
+		s.attackerOol = s.customOol
+		s.defenderOol = s.customOol
+	}
+}
+
+// simulationSeed returns the seed for the next simulation. Either the
+// configured seed or one picked from the clock.
+func (s *Simulator) simulationSeed() int64 {
+	if s.seeded {
+		return s.seed
+	}
+
+	return time.Now().UTC().UnixNano()
+}
+
+// worker returns a copy of the simulator rolling from its own random stream,
+// allowing it to resolve conflicts alongside other workers. The unit table and
+// ool slices are shared and must not be modified by the worker.
+func (s *Simulator) worker(seed int64) *Simulator {
+	w := *s
+	w.rng = rand.New(rand.NewSource(seed))
+	return &w
+}
+
+// checkIterations makes sure a simulation is able to resolve at least one
+// conflict, precise simulations ignoring the iterations set
+func (s *Simulator) checkIterations() error {
+	if s.margin > 0 && s.maxIterations <= 0 {
+		return &UnsupportedError{fmt.Sprintf("Precise simulations need a maximum number of iterations above 0, got %d", s.maxIterations)}
+	}
+	if s.margin <= 0 && s.iterations <= 0 {
+		return &UnsupportedError{fmt.Sprintf("Simulations need a number of iterations above 0, got %d", s.iterations)}
+	}
+
+	return nil
+}
+
+// simulate resolves the conflict once per iteration, or until the precision
+// requested by WithPrecision is reached. The returned accumulator holds every
+// conflict resolved, the error is that of ctx when it stopped the simulation.
+//
+// A precise simulation runs in waves, the precision being checked between
+// waves. Each wave is sized from the conflicts resolved so far, and every
+// conflict is resolved from the same stream as it would be in a fixed length
+// simulation, so seeded simulations stay reproducible.
+func (s *Simulator) simulate(ctx context.Context, attackers, defenders map[string]int, ool *conflictOol) (*summaryAccumulator, error) {
+	seed := s.simulationSeed()
+	acc := newSummaryAccumulator()
+	acc.attackers, acc.defenders = attackers, defenders
+	if s.assault != nil {
+		acc.attackers = copyFormation(attackers)
+		for _, units := range []map[string]int{s.assault.seaborne, s.assault.fleet} {
+			for alias, n := range units {
+				acc.attackers[alias] += n
+			}
+		}
+	}
+
+	if s.margin <= 0 {
+		return acc, s.runConflicts(ctx, acc, seed, s.iterations, s.iterations, attackers, defenders, ool)
+	}
+
+	to := precisionIterations
+	for {
+		if to > s.maxIterations {
+			to = s.maxIterations
+		}
+
+		err := s.runConflicts(ctx, acc, seed, to, s.maxIterations, attackers, defenders, ool)
+		if err != nil {
+			return acc, err
+		}
+
+		if to >= s.maxIterations || acc.winMargin() <= s.margin {
+			return acc, nil
+		}
+
+		next := acc.requiredIterations(s.margin)
+		if next < to+streamSize {
+			next = to + streamSize
+		}
+		to = (next + streamSize - 1) / streamSize * streamSize
+	}
+}
+
+// runConflicts resolves conflicts into acc through runStreams, until to
+// conflicts have been resolved. Each stream folds its conflicts into an
+// accumulator which is merged into acc as soon as the stream is resolved.
+// Memory use stays flat regardless of the number of conflicts.
+func (s *Simulator) runConflicts(ctx context.Context, acc *summaryAccumulator, seed int64, to, total int, attackers, defenders map[string]int, ool *conflictOol) error {
+	return s.runStreams(ctx, seed, acc.simulations, to, func(w *Simulator, first, last int) func() {
+		streamAcc := newSummaryAccumulator()
+		streamAcc.territoryValue = s.territoryValue
+		for j := first; j < last; j++ {
+			if j < s.sampleTraces {
+				profile, trace := w.traceConflict(attackers, defenders, ool)
+				trace.conflict = j
+				streamAcc.traces = append(streamAcc.traces, trace)
+				streamAcc.add(profile)
+				continue
+			}
+			streamAcc.add(w.resolveConflict(attackers, defenders, ool))
+		}
+
+		return func() {
+			acc.merge(streamAcc)
+			if s.progress != nil {
+				s.progress(acc.progress(total))
+			}
+		}
+	})
+}
+
+// runStreams resolves the iterations from up to to, on a pool of workers one
+// per CPU available to the process unless set by WithWorkers. The iterations
+// are split into streams, each resolved by resolve on a worker rolling from
+// the stream's own random source. The function returned by resolve is called
+// from a single goroutine once the stream is resolved, to gather its results.
+//
+// No new streams are started once ctx is done, only the streams resolved
+// before the cancellation are gathered and the error of ctx is returned.
+func (s *Simulator) runStreams(ctx context.Context, seed int64, from, to int, resolve func(w *Simulator, first, last int) func()) error {
+	workers := s.workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	type result struct {
+		iterations int
+		gather     func()
+	}
+
+	streams := make(chan int)
+	results := make(chan result)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for stream := range streams {
+				if ctx.Err() != nil {
+					continue
+				}
+
+				first, last := stream*streamSize, (stream+1)*streamSize
+				if last > to {
+					last = to
+				}
+				results <- result{last - first, resolve(s.worker(streamSeed(seed, stream)), first, last)}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(streams)
+		for stream := from / streamSize; stream*streamSize < to; stream++ {
+			select {
+			case streams <- stream:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	resolved := from
+	for r := range results {
+		resolved += r.iterations
+		r.gather()
+	}
+
+	if resolved < to {
+		return ctx.Err()
+	}
+
+	return nil
+}
+
+// streamSeed derives the seed of an independent random stream from the seed of
+// the simulation, using the splitmix64 finalizer to spread nearby streams
+// apart.
+func streamSeed(seed int64, stream int) int64 {
+	z := uint64(seed) + uint64(stream+1)*0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return int64(z ^ (z >> 31))
+}
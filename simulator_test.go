@@ -0,0 +1,241 @@
+package oddsengine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+func TestSimulatorOptions(t *testing.T) {
+	s := NewSimulator(
+		WithGame("1942"),
+		WithIterations(50),
+		WithMustTakeTerritory(true),
+		WithBaseOol([]string{"inf", "art", "tan"}),
+	)
+
+	if s.game != "1942" || s.iterations != 50 || s.keepLandUnits != 1 {
+		t.Errorf("simulator options were not applied\n%+v", s)
+	}
+	if s.units.HasUnit("mec") {
+		t.Errorf("a 1942 simulator should not have 1940 units")
+	}
+	if !reflect.DeepEqual(s.baseOol, []string{"inf", "art", "tan"}) {
+		t.Errorf("custom base ool was not applied\nactual: %v", s.baseOol)
+	}
+}
+
+// TestConcurrentSimulators runs differently configured simulators at the same
+// time, ensuring none of them share state.
+func TestConcurrentSimulators(t *testing.T) {
+	values := []struct {
+		game      string
+		attackers map[string]int
+		defenders map[string]int
+		valid     bool
+	}{
+		{"1940", map[string]int{"mec": 2, "tac": 1}, map[string]int{"inf": 2}, true},
+		{"1942", map[string]int{"mec": 2, "tac": 1}, map[string]int{"inf": 2}, false},
+		{"1941", map[string]int{"inf": 2, "tan": 1}, map[string]int{"inf": 2}, true},
+		{"deluxe", map[string]int{"hif": 2, "htk": 1}, map[string]int{"inf": 2, "cbf": 1}, true},
+	}
+
+	var wg sync.WaitGroup
+	for _, tt := range values {
+		wg.Add(1)
+		go func(game string, attackers, defenders map[string]int, valid bool) {
+			defer wg.Done()
+			s := NewSimulator(WithGame(game), WithIterations(100))
+			summary, err := s.GetSummary(attackers, defenders)
+			if (err == nil) != valid {
+				t.Errorf("summary validity for game %v was incorrect\nerror: %v", game, err)
+				return
+			}
+			if valid && summary.TotalSimulations != 100 {
+				t.Errorf("summary for game %v did not run all simulations\nactual: %v", game, summary.TotalSimulations)
+			}
+		}(tt.game, tt.attackers, tt.defenders, tt.valid)
+	}
+	wg.Wait()
+}
+
+// TestSeededSimulationsAreReproducible ensures that a seeded simulator
+// produces byte identical summaries, no matter how many run in parallel.
+func TestSeededSimulationsAreReproducible(t *testing.T) {
+	attackers := map[string]int{"inf": 4, "art": 2, "tan": 2, "fig": 1}
+	defenders := map[string]int{"aaa": 1, "inf": 5, "tan": 1, "fig": 1}
+
+	summarize := func(seed int64, procs int) []byte {
+		defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(procs))
+
+		s := NewSimulator(WithIterations(1050), WithSeed(seed))
+		summary, err := s.GetSummary(attackers, defenders)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b, err := json.Marshal(summary)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	expected := summarize(42, 1)
+	if actual := summarize(42, 4); !bytes.Equal(expected, actual) {
+		t.Errorf("seeded summaries did not match\nexpected: %s\nactual: %s", expected, actual)
+	}
+	if actual := summarize(43, 4); bytes.Equal(expected, actual) {
+		t.Errorf("different seeds produced the same summary\n%s", actual)
+	}
+}
+
+// TestSummaryContextCancellation stops a simulation part way through from the
+// progress callback and ensures the partial summary is flagged.
+func TestSummaryContextCancellation(t *testing.T) {
+	attackers := map[string]int{"inf": 4, "art": 2, "tan": 2}
+	defenders := map[string]int{"inf": 5, "tan": 1}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := NewSimulator(WithIterations(100000), WithSeed(1), WithProgress(func(p Progress) {
+		if p.CompletedIterations >= 1000 {
+			cancel()
+		}
+	}))
+
+	summary, err := s.GetSummaryContext(ctx, attackers, defenders)
+	if err != context.Canceled {
+		t.Errorf("expected the context error, actual: %v", err)
+	}
+	if !summary.Incomplete || summary.TotalSimulations < 1000 || summary.TotalSimulations >= 100000 {
+		t.Errorf("partial summary was not generated correctly\n%+v", summary)
+	}
+	if summary.AttackerWinPercentage == 0 {
+		t.Errorf("partial summary should contain the resolved conflicts\n%+v", summary)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	summary, err = s.GetSummaryContext(ctx, attackers, defenders)
+	if err != context.Canceled || !summary.Incomplete || summary.TotalSimulations != 0 {
+		t.Errorf("a cancelled context should not resolve any conflicts\nerror: %v\n%+v", err, summary)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	var reports []Progress
+	s := NewSimulator(WithIterations(1050), WithSeed(1), WithProgress(func(p Progress) {
+		reports = append(reports, p)
+	}))
+
+	summary, err := s.GetSummary(map[string]int{"inf": 3, "tan": 1}, map[string]int{"inf": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Incomplete {
+		t.Errorf("a finished simulation should not be incomplete")
+	}
+
+	if len(reports) != 11 {
+		t.Fatalf("expected a report per stream, actual: %v", len(reports))
+	}
+	for i, p := range reports {
+		if p.TotalIterations != 1050 || (i > 0 && p.CompletedIterations <= reports[i-1].CompletedIterations) {
+			t.Errorf("progress was not reported correctly\n%+v", reports)
+			break
+		}
+	}
+
+	last := reports[len(reports)-1]
+	if last.CompletedIterations != 1050 ||
+		last.AttackerWinPercentage != summary.AttackerWinPercentage ||
+		last.DefenderWinPercentage != summary.DefenderWinPercentage ||
+		last.DrawPercentage != summary.DrawPercentage {
+		t.Errorf("final progress did not match the summary\nprogress: %+v\nsummary: %+v", last, summary)
+	}
+}
+
+func TestStreamSeed(t *testing.T) {
+	seen := map[int64]bool{}
+	for stream := 0; stream < 1000; stream++ {
+		seed := streamSeed(1, stream)
+		if seen[seed] {
+			t.Errorf("stream %v repeated a seed", stream)
+		}
+		seen[seed] = true
+
+		if streamSeed(1, stream) != seed {
+			t.Errorf("stream %v seed is not stable", stream)
+		}
+	}
+}
+
+func TestPrecision(t *testing.T) {
+	values := []struct {
+		attackers map[string]int
+		defenders map[string]int
+		margin    float64
+		max       int
+	}{
+		// A close conflict needs many iterations
+		{map[string]int{"inf": 4, "art": 2, "tan": 2}, map[string]int{"inf": 5, "tan": 1}, 1, 100000},
+		// A lopsided conflict needs few
+		{map[string]int{"tan": 10}, map[string]int{"inf": 2}, 1, 100000},
+		// The ceiling is never exceeded
+		{map[string]int{"inf": 4, "art": 2, "tan": 2}, map[string]int{"inf": 5, "tan": 1}, 0.1, 5050},
+	}
+
+	for _, tt := range values {
+		s := NewSimulator(WithPrecision(tt.margin, tt.max), WithSeed(1))
+		summary, err := s.GetSummary(tt.attackers, tt.defenders)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if summary.TotalSimulations > tt.max {
+			t.Errorf("precise simulation exceeded its ceiling\n%+v", summary)
+		}
+
+		margin := func(i Interval) float64 { return (i.Upper - i.Lower) / 2 }
+		reached := margin(summary.Confidence.AttackerWinPercentage) <= tt.margin+0.01 &&
+			margin(summary.Confidence.DefenderWinPercentage) <= tt.margin+0.01
+		if reached == (summary.TotalSimulations == tt.max) {
+			t.Errorf("precise simulation stopped at the wrong time\n%+v", summary)
+		}
+
+		again, _ := s.GetSummary(tt.attackers, tt.defenders)
+		if !reflect.DeepEqual(summary, again) {
+			t.Errorf("seeded precise simulations did not match\nexpected: %+v\nactual: %+v", summary, again)
+		}
+	}
+
+	// A simulation must be able to resolve a conflict, precise simulations
+	// ignoring the iterations set
+	checks := []struct {
+		opts     []Option
+		rejected bool
+	}{
+		{[]Option{WithPrecision(1, 0)}, true},
+		{[]Option{WithPrecision(1, -10)}, true},
+		{[]Option{WithIterations(0)}, true},
+		{[]Option{WithIterations(-5)}, true},
+		{[]Option{WithIterations(0), WithPrecision(1, 1000)}, false},
+	}
+	for _, tt := range checks {
+		s := NewSimulator(tt.opts...)
+		_, err := s.GetSummary(map[string]int{"inf": 1}, map[string]int{"inf": 1})
+		if _, ok := err.(*UnsupportedError); ok != tt.rejected {
+			t.Errorf("simulation of %d iterations, %d at most, was not checked\nerror: %v", s.iterations, s.maxIterations, err)
+		}
+		_, err = s.GetAmphibiousSummary(map[string]int{}, map[string]int{"inf": 1}, AmphibiousAssault{Seaborne: map[string]int{"inf": 1}})
+		if _, ok := err.(*UnsupportedError); ok != tt.rejected {
+			t.Errorf("assault of %d iterations, %d at most, was not checked\nerror: %v", s.iterations, s.maxIterations, err)
+		}
+	}
+}
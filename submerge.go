@@ -0,0 +1,43 @@
+package oddsengine
+
+// SubmergePolicy describes when a side's submarines submerge, leaving the
+// conflict. Submarines may only submerge while the enemy has no destroyer.
+// Rules left at their zero value are ignored, the zero SubmergePolicy never
+// submerges.
+type SubmergePolicy struct {
+	// BeforeRound submerges the submarines before this round is fought. A
+	// value of 1 submerges them before they fire a single shot
+	BeforeRound int `json:"beforeRound"`
+
+	// WhenLosingBy submerges the submarines once the side has lost this many
+	// IPCs more than the enemy
+	WhenLosingBy int `json:"whenLosingBy"`
+}
+
+// shouldSubmerge returns whether the submarines following the policy submerge
+// before the passed in round, given the IPCs lost by their side and the enemy.
+func (p SubmergePolicy) shouldSubmerge(round, ipcLoss, enemyIpcLoss int) bool {
+	if p.BeforeRound > 0 && round >= p.BeforeRound {
+		return true
+	}
+
+	return p.WhenLosingBy > 0 && ipcLoss-enemyIpcLoss >= p.WhenLosingBy
+}
+
+// submerge removes the submarines from the formation f, adding them to the
+// submerged formation. Subs only submerge when the enemy has no destroyer.
+// Returns whether any submarines submerged.
+func (s *Simulator) submerge(f, enemy, submerged map[string]int) bool {
+	if !s.hasSub(f) || hasUnit(enemy, "des") {
+		return false
+	}
+
+	for alias, n := range f {
+		if sliceHasValue(s.subs, realAlias(alias)) {
+			submerged[alias] += n
+			delete(f, alias)
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,68 @@
+package oddsengine
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestShouldSubmerge(t *testing.T) {
+	values := []struct {
+		policy       SubmergePolicy
+		round        int
+		ipcLoss      int
+		enemyIpcLoss int
+		submerge     bool
+	}{
+		{SubmergePolicy{}, 5, 30, 0, false},
+		{SubmergePolicy{BeforeRound: 1}, 1, 0, 0, true},
+		{SubmergePolicy{BeforeRound: 3}, 2, 0, 0, false},
+		{SubmergePolicy{BeforeRound: 3}, 4, 0, 0, true},
+		{SubmergePolicy{WhenLosingBy: 6}, 2, 12, 6, true},
+		{SubmergePolicy{WhenLosingBy: 6}, 2, 12, 8, false},
+	}
+
+	for _, tt := range values {
+		if tt.policy.shouldSubmerge(tt.round, tt.ipcLoss, tt.enemyIpcLoss) != tt.submerge {
+			t.Errorf("submerge was decided incorrectly\npolicy: %+v\nround: %v\nipcLoss: %v\nenemyIpcLoss: %v", tt.policy, tt.round, tt.ipcLoss, tt.enemyIpcLoss)
+		}
+	}
+}
+
+func TestSubmergeConflict(t *testing.T) {
+	s := NewSimulator(WithDefenderSubmerge(SubmergePolicy{BeforeRound: 1}))
+
+	// The defending subs leave before a shot is fired
+	cruiser, subs := map[string]int{"cru": 1}, map[string]int{"sub": 2}
+	profile := s.resolveConflict(cruiser, subs, s.customizeOol(cruiser, subs))
+	if profile.Rounds != 0 || profile.Outcome != AttackerWin || profile.DefenderIpcLoss != 0 ||
+		!reflect.DeepEqual(profile.DefenderUnitsSubmerged, formationToSortedSlice(map[string]int{"sub": 2})) {
+		t.Errorf("defending subs did not submerge\n%+v", profile)
+	}
+
+	// Subs cannot submerge from a destroyer
+	destroyer := map[string]int{"des": 1}
+	profile = s.resolveConflict(destroyer, subs, s.customizeOol(destroyer, subs))
+	if profile.Rounds == 0 || len(profile.DefenderUnitsSubmerged) != 0 {
+		t.Errorf("defending subs submerged in the presence of a destroyer\n%+v", profile)
+	}
+
+	s = NewSimulator(WithAttackerSubmerge(SubmergePolicy{BeforeRound: 2}), WithIterations(1000), WithSeed(1))
+	summary, err := s.GetSummary(map[string]int{"sub": 3, "cru": 1}, map[string]int{"bat": 1, "cru": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	submerged := countFormations(summary.AttackerUnitsSubmerged)
+	if submerged == 0 || float64(submerged)/10 != summary.AttackerSubmergedPercentage || summary.DefenderSubmergedPercentage != 0 {
+		t.Errorf("submerged units were not summarized\n%+v", summary)
+	}
+	if conf := summary.Confidence; conf.AttackerSubmergedPercentage != wilsonInterval(float64(submerged), 1000) ||
+		conf.DefenderSubmergedPercentage != wilsonInterval(0, 1000) {
+		t.Errorf("submerged percentages have no confidence intervals\n%+v", summary.Confidence)
+	}
+	for formation := range summary.AttackerUnitsSubmerged {
+		if formation != "sub:1" && formation != "sub:2" && formation != "sub:3" {
+			t.Errorf("units other than subs submerged\n%v", formation)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package oddsengine
 
 import (
 	"math"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -12,6 +13,14 @@ type Summary struct {
 	// TotalSimulations The number of simulations that have been ran
 	TotalSimulations int `json:"totalSimulations"`
 
+	// Exact is true when the summary was calculated exactly rather than by
+	// running simulations
+	Exact bool `json:"exact"`
+
+	// Incomplete is true when the simulation was stopped before every
+	// iteration was ran. The summary only covers TotalSimulations conflicts
+	Incomplete bool `json:"incomplete"`
+
 	// AverageRounds The number of rounds on average a conflict lasted.
 	AverageRounds float64 `json:"averageRounds"`
 
@@ -24,6 +33,26 @@ type Summary struct {
 	// DrawPercentage The percentage of conflicts that was a draw
 	DrawPercentage float64 `json:"drawPercentage"`
 
+	// AttackerRetreatPercentage The percentage of conflicts that the attacker
+	// retreated from
+	AttackerRetreatPercentage float64 `json:"attackerRetreatPercentage"`
+
+	// TerritoryCapturedPercentage The percentage of conflicts that the
+	// attacker won with land units left to take the territory
+	TerritoryCapturedPercentage float64 `json:"territoryCapturedPercentage"`
+
+	// AttackerSubmergedPercentage The percentage of conflicts where the
+	// attacker's submarines submerged
+	AttackerSubmergedPercentage float64 `json:"attackerSubmergedPercentage"`
+
+	// DefenderSubmergedPercentage The percentage of conflicts where the
+	// defender's submarines submerged
+	DefenderSubmergedPercentage float64 `json:"defenderSubmergedPercentage"`
+
+	// UnresolvedPercentage The percentage of conflicts that were still going
+	// when the maximum number of rounds had been fought
+	UnresolvedPercentage float64 `json:"unresolvedPercentage"`
+
 	// AAAHitsAverage The number of AAA hits per round on average
 	AAAHitsAverage float64 `json:"aaaHitsAverage"`
 
@@ -36,11 +65,33 @@ type Summary struct {
 	// DefenderAvgIpcLoss The number of IPC's the defender loses on average
 	DefenderAvgIpcLoss float64 `json:"defenderAvgIpcLoss"`
 
+	// AvgIpcSwing The net number of IPC's the attacker gains on average. The
+	// defender's loss less the attacker's, plus the territory's value whenever
+	// it is captured
+	AvgIpcSwing float64 `json:"avgIpcSwing"`
+
+	// Confidence holds the standard error and 95% confidence interval of each
+	// of the percentages and averages above
+	Confidence Confidence `json:"confidence"`
+
+	// Distributions holds the histogram and percentiles of each of the
+	// averages above, for telling a reliably cheap attack from one that is
+	// usually cheap but sometimes a disaster
+	Distributions Distributions `json:"distributions"`
+
 	// FirstRoundResults is the array of first round data. Represents the
 	// number of hits that an attacker and defender get on the first round,
 	// the frequency of such a result, and the victory result of that conflict.
 	FirstRoundResults FirstRoundResultCollection `json:"firstRoundResults"`
 
+	// Rounds is how the conflicts stood at the end of each round, for
+	// deciding when to press on
+	Rounds []RoundSummary `json:"rounds"`
+
+	// Traces are the traces of the first conflicts simulated, as many as set
+	// by WithSampleTraces
+	Traces []*Trace `json:"traces,omitempty"`
+
 	// AttackerUnitsRemaining represents all the remaining units at the end
 	// of conflict. The units are represented by a string and the number of
 	// times that that formation remained at the end of the conflict is the
@@ -52,84 +103,418 @@ type Summary struct {
 	// times that that formation remained at the end of the conflict is the
 	// value
 	DefenderUnitsRemaining map[string]int `json:"defenderUnitsRemaining"`
+
+	// AttackerUnitSurvival is how each kind of attacking unit fared, by alias,
+	// across every conflict whatever its outcome
+	AttackerUnitSurvival map[string]UnitSurvival `json:"attackerUnitSurvival"`
+
+	// DefenderUnitSurvival is how each kind of defending unit fared, by alias,
+	// across every conflict whatever its outcome
+	DefenderUnitSurvival map[string]UnitSurvival `json:"defenderUnitSurvival"`
+
+	// AttackerUnitsSubmerged represents the attacking units that submerged,
+	// neither lost nor remaining at the end of the conflict. The units are
+	// represented by a string and the number of times that that formation
+	// submerged is the value
+	AttackerUnitsSubmerged map[string]int `json:"attackerUnitsSubmerged"`
+
+	// DefenderUnitsSubmerged represents the defending units that submerged,
+	// neither lost nor remaining at the end of the conflict. The units are
+	// represented by a string and the number of times that that formation
+	// submerged is the value
+	DefenderUnitsSubmerged map[string]int `json:"defenderUnitsSubmerged"`
+
+	// UnresolvedAttackerUnitsRemaining represents the attacking units
+	// remaining in the conflicts left unresolved. The units are represented by
+	// a string and the number of times that that formation remained is the
+	// value
+	UnresolvedAttackerUnitsRemaining map[string]int `json:"unresolvedAttackerUnitsRemaining"`
+
+	// UnresolvedDefenderUnitsRemaining represents the defending units
+	// remaining in the conflicts left unresolved. The units are represented by
+	// a string and the number of times that that formation remained is the
+	// value
+	UnresolvedDefenderUnitsRemaining map[string]int `json:"unresolvedDefenderUnitsRemaining"`
 }
 
 // generateSummary Creates a summary from a slice of profiles.
 func generateSummary(p []ConflictProfile) *Summary {
+	acc := newSummaryAccumulator()
+	for i := range p {
+		acc.add(&p[i])
+	}
 
-	var summary Summary
-	summary.AttackerUnitsRemaining = map[string]int{}
-	summary.DefenderUnitsRemaining = map[string]int{}
-	summary.TotalSimulations = len(p)
-
-	var totalRounds float64
-	var totalAAAHits float64
-	var totalKamikazeHits float64
-	var totalAttackerWins float64
-	var totalDefenderWins float64
-	var totalDraw float64
-	var totalAttackerIpcLoss float64
-	var totalDefenderIpcLoss float64
-
-	for _, profile := range p {
-
-		if profile.Outcome == 0 {
-			totalDraw++
-		} else if profile.Outcome == 1 {
-			totalAttackerWins++
-
-			attackerRemainingString := formationSliceToString(profile.AttackerUnitsRemaining)
-
-			if _, ok := summary.AttackerUnitsRemaining[attackerRemainingString]; ok {
-				summary.AttackerUnitsRemaining[attackerRemainingString]++
-			} else {
-				summary.AttackerUnitsRemaining[attackerRemainingString] = 1
-			}
-		} else if profile.Outcome == -1 {
-			totalDefenderWins++
-
-			defenderRemainingString := formationSliceToString(profile.DefenderUnitsRemaining)
-
-			if _, ok := summary.DefenderUnitsRemaining[defenderRemainingString]; ok {
-				summary.DefenderUnitsRemaining[defenderRemainingString]++
-			} else {
-				summary.DefenderUnitsRemaining[defenderRemainingString] = 1
-			}
-		}
+	return acc.summary()
+}
+
+// summaryAccumulator folds ConflictProfiles into the running totals a Summary
+// is built from, so the profiles themselves never need to be kept. Partial
+// accumulators may be merged together in any order.
+type summaryAccumulator struct {
+	simulations            int
+	totalRounds            float64
+	totalAAAHits           float64
+	totalKamikazeHits      float64
+	totalAttackerWins      float64
+	totalDefenderWins      float64
+	totalDraw              float64
+	totalAttackerRetreat   float64
+	totalTerritoryCaptured float64
+	totalUnresolved        float64
+	totalAttackerIpcLoss   float64
+	totalDefenderIpcLoss   float64
+	totalIpcSwing          float64
+
+	// The sums of squares of the averaged values, used to calculate their
+	// standard errors
+	totalRoundsSquared          float64
+	totalAAAHitsSquared         float64
+	totalKamikazeHitsSquared    float64
+	totalAttackerIpcLossSquared float64
+	totalDefenderIpcLossSquared float64
+	totalIpcSwingSquared        float64
 
+	// territoryValue is the value of the territory counted in the IPC swing
+	// of every conflict added
+	territoryValue int
+
+	// The number of conflicts by each of the values averaged, from which
+	// their distributions are created
+	roundsHistogram          map[int]int
+	aaaHitsHistogram         map[int]int
+	kamikazeHitsHistogram    map[int]int
+	attackerIpcLossHistogram map[int]int
+	defenderIpcLossHistogram map[int]int
+	ipcSwingHistogram        map[int]int
+
+	rounds                 []roundTotals
+	traces                 []*Trace
+	firstRoundResults      FirstRoundResultCollection
+	attackerUnitsRemaining map[string]int
+	defenderUnitsRemaining map[string]int
+
+	unresolvedAttackerUnitsRemaining map[string]int
+	unresolvedDefenderUnitsRemaining map[string]int
+	attackerUnitsSubmerged           map[string]int
+	defenderUnitsSubmerged           map[string]int
+
+	// The number of conflicts by the number of units of each alias surviving
+	attackerSurvivors map[string]map[int]int
+	defenderSurvivors map[string]map[int]int
+
+	// attackers and defenders are the units the conflicts started with, so
+	// units which never survived are part of the summary too
+	attackers map[string]int
+	defenders map[string]int
+}
+
+// newSummaryAccumulator returns an empty summaryAccumulator
+func newSummaryAccumulator() *summaryAccumulator {
+	return &summaryAccumulator{
+		roundsHistogram:          map[int]int{},
+		aaaHitsHistogram:         map[int]int{},
+		kamikazeHitsHistogram:    map[int]int{},
+		attackerIpcLossHistogram: map[int]int{},
+		defenderIpcLossHistogram: map[int]int{},
+		ipcSwingHistogram:        map[int]int{},
+
+		attackerUnitsRemaining: map[string]int{},
+		defenderUnitsRemaining: map[string]int{},
+
+		unresolvedAttackerUnitsRemaining: map[string]int{},
+		unresolvedDefenderUnitsRemaining: map[string]int{},
+		attackerUnitsSubmerged:           map[string]int{},
+		defenderUnitsSubmerged:           map[string]int{},
+
+		attackerSurvivors: map[string]map[int]int{},
+		defenderSurvivors: map[string]map[int]int{},
+	}
+}
+
+// add records a single profile into the accumulator
+func (a *summaryAccumulator) add(profile *ConflictProfile) {
+	a.simulations++
+
+	if profile.Outcome == Draw {
+		a.totalDraw++
+	} else if profile.Outcome == AttackerWin {
+		a.totalAttackerWins++
+		a.attackerUnitsRemaining[formationSliceToString(profile.AttackerUnitsRemaining)]++
+	} else if profile.Outcome == DefenderWin {
+		a.totalDefenderWins++
+		a.defenderUnitsRemaining[formationSliceToString(profile.DefenderUnitsRemaining)]++
+	} else if profile.Outcome == AttackerRetreat {
+		a.totalAttackerRetreat++
+	} else if profile.Outcome == Unresolved {
+		a.totalUnresolved++
+		a.unresolvedAttackerUnitsRemaining[formationSliceToString(profile.AttackerUnitsRemaining)]++
+		a.unresolvedDefenderUnitsRemaining[formationSliceToString(profile.DefenderUnitsRemaining)]++
+	}
+
+	swing := profile.DefenderIpcLoss - profile.AttackerIpcLoss
+	if profile.TerritoryCaptured {
+		a.totalTerritoryCaptured++
+		swing += a.territoryValue
+	}
+	a.totalIpcSwing += float64(swing)
+	a.totalIpcSwingSquared += float64(swing * swing)
+	a.ipcSwingHistogram[swing]++
+
+	if len(profile.AttackerUnitsSubmerged) > 0 {
+		a.attackerUnitsSubmerged[formationSliceToString(profile.AttackerUnitsSubmerged)]++
+	}
+	if len(profile.DefenderUnitsSubmerged) > 0 {
+		a.defenderUnitsSubmerged[formationSliceToString(profile.DefenderUnitsSubmerged)]++
+	}
+
+	// A conflict may be over before a single round is fought, in which case
+	// there is no first round to record.
+	if profile.Rounds > 0 {
 		firstRoundResult := FirstRoundResult{
 			AttackerHits: profile.AttackerHits[0],
 			DefenderHits: profile.DefenderHits[0],
 			Frequency:    1,
 		}
 
-		if profile.Outcome == 0 {
+		if profile.Outcome == Draw {
 			firstRoundResult.Draw = 1
-		} else if profile.Outcome == 1 {
+		} else if profile.Outcome == AttackerWin {
 			firstRoundResult.AttackerWin = 1
+		} else if profile.Outcome == AttackerRetreat {
+			firstRoundResult.AttackerRetreat = 1
+		} else if profile.Outcome == Unresolved {
+			firstRoundResult.Unresolved = 1
 		} else {
 			firstRoundResult.DefenderWin = 1
 		}
-		summary.FirstRoundResults = summary.FirstRoundResults.Add(firstRoundResult)
-
-		totalRounds += float64(profile.Rounds)
-		totalAttackerIpcLoss += float64(profile.AttackerIpcLoss)
-		totalDefenderIpcLoss += float64(profile.DefenderIpcLoss)
-		totalAAAHits += float64(profile.AAAHits)
-		totalKamikazeHits += float64(profile.KamikazeHits)
-	}
-	summary.AttackerWinPercentage = round((totalAttackerWins/float64(len(p)))*100, 2)
-	summary.DefenderWinPercentage = round((totalDefenderWins/float64(len(p)))*100, 2)
-	summary.DrawPercentage = round((totalDraw/float64(len(p)))*100, 2)
-	summary.AttackerAvgIpcLoss = round((totalAttackerIpcLoss / float64(len(p))), 2)
-	summary.AAAHitsAverage = round((totalAAAHits / float64(len(p))), 2)
-	summary.KamikazeHitsAverage = round((totalKamikazeHits / float64(len(p))), 2)
-	summary.DefenderAvgIpcLoss = round((totalDefenderIpcLoss / float64(len(p))), 2)
-	summary.AverageRounds = round((totalRounds / float64(len(p))), 2)
+		a.firstRoundResults = a.firstRoundResults.Add(firstRoundResult)
+	}
+
+	a.totalRounds += float64(profile.Rounds)
+	a.totalAttackerIpcLoss += float64(profile.AttackerIpcLoss)
+	a.totalDefenderIpcLoss += float64(profile.DefenderIpcLoss)
+	a.totalAAAHits += float64(profile.AAAHits)
+	a.totalKamikazeHits += float64(profile.KamikazeHits)
+
+	a.totalRoundsSquared += float64(profile.Rounds * profile.Rounds)
+	a.totalAttackerIpcLossSquared += float64(profile.AttackerIpcLoss * profile.AttackerIpcLoss)
+	a.totalDefenderIpcLossSquared += float64(profile.DefenderIpcLoss * profile.DefenderIpcLoss)
+	a.totalAAAHitsSquared += float64(profile.AAAHits * profile.AAAHits)
+	a.totalKamikazeHitsSquared += float64(profile.KamikazeHits * profile.KamikazeHits)
+
+	addSurvivors(a.attackerSurvivors, profile.AttackerUnitsRemaining, profile.AttackerUnitsSubmerged, profile.AttackerUnitsBombarding, profile.AttackerUnitsAtSea)
+	addSurvivors(a.defenderSurvivors, profile.DefenderUnitsRemaining, profile.DefenderUnitsSubmerged)
+
+	a.rounds = addRounds(a.rounds, profile)
+
+	a.roundsHistogram[profile.Rounds]++
+	a.aaaHitsHistogram[profile.AAAHits]++
+	a.kamikazeHitsHistogram[profile.KamikazeHits]++
+	a.attackerIpcLossHistogram[profile.AttackerIpcLoss]++
+	a.defenderIpcLossHistogram[profile.DefenderIpcLoss]++
+}
+
+// merge folds another accumulator into this one
+func (a *summaryAccumulator) merge(b *summaryAccumulator) {
+	a.simulations += b.simulations
+	a.totalRounds += b.totalRounds
+	a.totalAAAHits += b.totalAAAHits
+	a.totalKamikazeHits += b.totalKamikazeHits
+	a.totalAttackerWins += b.totalAttackerWins
+	a.totalDefenderWins += b.totalDefenderWins
+	a.totalDraw += b.totalDraw
+	a.totalAttackerRetreat += b.totalAttackerRetreat
+	a.totalTerritoryCaptured += b.totalTerritoryCaptured
+	a.totalUnresolved += b.totalUnresolved
+	a.totalAttackerIpcLoss += b.totalAttackerIpcLoss
+	a.totalDefenderIpcLoss += b.totalDefenderIpcLoss
+	a.totalIpcSwing += b.totalIpcSwing
+	a.totalRoundsSquared += b.totalRoundsSquared
+	a.totalAAAHitsSquared += b.totalAAAHitsSquared
+	a.totalKamikazeHitsSquared += b.totalKamikazeHitsSquared
+	a.totalAttackerIpcLossSquared += b.totalAttackerIpcLossSquared
+	a.totalDefenderIpcLossSquared += b.totalDefenderIpcLossSquared
+	a.totalIpcSwingSquared += b.totalIpcSwingSquared
+
+	mergeSurvivors(a.attackerSurvivors, b.attackerSurvivors)
+	mergeSurvivors(a.defenderSurvivors, b.defenderSurvivors)
+
+	a.rounds = mergeRounds(a.rounds, b.rounds)
+	a.traces = append(a.traces, b.traces...)
+
+	mergeHistogram(a.roundsHistogram, b.roundsHistogram)
+	mergeHistogram(a.aaaHitsHistogram, b.aaaHitsHistogram)
+	mergeHistogram(a.kamikazeHitsHistogram, b.kamikazeHitsHistogram)
+	mergeHistogram(a.attackerIpcLossHistogram, b.attackerIpcLossHistogram)
+	mergeHistogram(a.defenderIpcLossHistogram, b.defenderIpcLossHistogram)
+	mergeHistogram(a.ipcSwingHistogram, b.ipcSwingHistogram)
+
+	for _, result := range b.firstRoundResults {
+		a.firstRoundResults = a.firstRoundResults.Add(result)
+	}
+	for formation, n := range b.attackerUnitsRemaining {
+		a.attackerUnitsRemaining[formation] += n
+	}
+	for formation, n := range b.defenderUnitsRemaining {
+		a.defenderUnitsRemaining[formation] += n
+	}
+	for formation, n := range b.unresolvedAttackerUnitsRemaining {
+		a.unresolvedAttackerUnitsRemaining[formation] += n
+	}
+	for formation, n := range b.unresolvedDefenderUnitsRemaining {
+		a.unresolvedDefenderUnitsRemaining[formation] += n
+	}
+	for formation, n := range b.attackerUnitsSubmerged {
+		a.attackerUnitsSubmerged[formation] += n
+	}
+	for formation, n := range b.defenderUnitsSubmerged {
+		a.defenderUnitsSubmerged[formation] += n
+	}
+}
+
+// summary creates the Summary of everything accumulated so far
+func (a *summaryAccumulator) summary() *Summary {
+	var summary Summary
+	summary.TotalSimulations = a.simulations
+	summary.AttackerUnitsRemaining = copyFormation(a.attackerUnitsRemaining)
+	summary.DefenderUnitsRemaining = copyFormation(a.defenderUnitsRemaining)
+	summary.UnresolvedAttackerUnitsRemaining = copyFormation(a.unresolvedAttackerUnitsRemaining)
+	summary.UnresolvedDefenderUnitsRemaining = copyFormation(a.unresolvedDefenderUnitsRemaining)
+	summary.AttackerUnitsSubmerged = copyFormation(a.attackerUnitsSubmerged)
+	summary.DefenderUnitsSubmerged = copyFormation(a.defenderUnitsSubmerged)
+
+	if a.simulations == 0 {
+		return &summary
+	}
+
+	total := float64(a.simulations)
+	summary.AttackerWinPercentage = round((a.totalAttackerWins/total)*100, 2)
+	summary.DefenderWinPercentage = round((a.totalDefenderWins/total)*100, 2)
+	summary.DrawPercentage = round((a.totalDraw/total)*100, 2)
+	summary.AttackerRetreatPercentage = round((a.totalAttackerRetreat/total)*100, 2)
+	summary.UnresolvedPercentage = round((a.totalUnresolved/total)*100, 2)
+	summary.TerritoryCapturedPercentage = round((a.totalTerritoryCaptured/total)*100, 2)
+	summary.AttackerSubmergedPercentage = round((float64(countFormations(a.attackerUnitsSubmerged))/total)*100, 2)
+	summary.DefenderSubmergedPercentage = round((float64(countFormations(a.defenderUnitsSubmerged))/total)*100, 2)
+	summary.AttackerAvgIpcLoss = round((a.totalAttackerIpcLoss / total), 2)
+	summary.AAAHitsAverage = round((a.totalAAAHits / total), 2)
+	summary.KamikazeHitsAverage = round((a.totalKamikazeHits / total), 2)
+	summary.DefenderAvgIpcLoss = round((a.totalDefenderIpcLoss / total), 2)
+	summary.AvgIpcSwing = round((a.totalIpcSwing / total), 2)
+	summary.AverageRounds = round((a.totalRounds / total), 2)
+
+	summary.Confidence = Confidence{
+		AverageRounds:               normalInterval(a.totalRounds, a.totalRoundsSquared, total),
+		AttackerWinPercentage:       wilsonInterval(a.totalAttackerWins, total),
+		DefenderWinPercentage:       wilsonInterval(a.totalDefenderWins, total),
+		DrawPercentage:              wilsonInterval(a.totalDraw, total),
+		AttackerRetreatPercentage:   wilsonInterval(a.totalAttackerRetreat, total),
+		UnresolvedPercentage:        wilsonInterval(a.totalUnresolved, total),
+		TerritoryCapturedPercentage: wilsonInterval(a.totalTerritoryCaptured, total),
+		AttackerSubmergedPercentage: wilsonInterval(float64(countFormations(a.attackerUnitsSubmerged)), total),
+		DefenderSubmergedPercentage: wilsonInterval(float64(countFormations(a.defenderUnitsSubmerged)), total),
+		AAAHitsAverage:              normalInterval(a.totalAAAHits, a.totalAAAHitsSquared, total),
+		KamikazeHitsAverage:         normalInterval(a.totalKamikazeHits, a.totalKamikazeHitsSquared, total),
+		AttackerAvgIpcLoss:          normalInterval(a.totalAttackerIpcLoss, a.totalAttackerIpcLossSquared, total),
+		DefenderAvgIpcLoss:          normalInterval(a.totalDefenderIpcLoss, a.totalDefenderIpcLossSquared, total),
+		AvgIpcSwing:                 signedNormalInterval(a.totalIpcSwing, a.totalIpcSwingSquared, total),
+	}
+
+	summary.AttackerUnitSurvival = unitSurvival(a.attackerSurvivors, a.attackers, a.simulations)
+	summary.DefenderUnitSurvival = unitSurvival(a.defenderSurvivors, a.defenders, a.simulations)
+
+	summary.Rounds = roundSummaries(a.rounds, total)
+
+	if len(a.traces) > 0 {
+		summary.Traces = append([]*Trace{}, a.traces...)
+		sortTraces(summary.Traces)
+	}
+
+	summary.Distributions = Distributions{
+		Rounds:          distribution(a.roundsHistogram),
+		AAAHits:         distribution(a.aaaHitsHistogram),
+		KamikazeHits:    distribution(a.kamikazeHitsHistogram),
+		AttackerIpcLoss: distribution(a.attackerIpcLossHistogram),
+		DefenderIpcLoss: distribution(a.defenderIpcLossHistogram),
+		IpcSwing:        distribution(a.ipcSwingHistogram),
+	}
+
+	// Profiles arrive in whatever order the conflicts finished, sort the first
+	// round results so the summary does not depend on that order.
+	summary.FirstRoundResults = append(FirstRoundResultCollection{}, a.firstRoundResults...)
+	sort.Slice(summary.FirstRoundResults, func(i, j int) bool {
+		a, b := summary.FirstRoundResults[i], summary.FirstRoundResults[j]
+		if a.AttackerHits == b.AttackerHits {
+			return a.DefenderHits < b.DefenderHits
+		}
+		return a.AttackerHits < b.AttackerHits
+	})
 
 	return &summary
 }
 
+// progress reports the running win percentages of everything accumulated so
+// far, out of a total number of iterations
+func (a *summaryAccumulator) progress(total int) Progress {
+	p := Progress{
+		CompletedIterations: a.simulations,
+		TotalIterations:     total,
+	}
+
+	if a.simulations == 0 {
+		return p
+	}
+
+	n := float64(a.simulations)
+	p.AttackerWinPercentage = round((a.totalAttackerWins/n)*100, 2)
+	p.DefenderWinPercentage = round((a.totalDefenderWins/n)*100, 2)
+	p.DrawPercentage = round((a.totalDraw/n)*100, 2)
+
+	return p
+}
+
+// winMargin returns the margin of error, in percentage points, of the less
+// precise of the attacker and defender win percentages
+func (a *summaryAccumulator) winMargin() float64 {
+	if a.simulations == 0 {
+		return 100
+	}
+
+	n := float64(a.simulations)
+	_, attacker := wilson(a.totalAttackerWins, n)
+	_, defender := wilson(a.totalDefenderWins, n)
+
+	return math.Max(attacker, defender) * 100
+}
+
+// requiredIterations estimates the number of conflicts needed for the win
+// percentages to reach margin, based on the win rates accumulated so far
+func (a *summaryAccumulator) requiredIterations(margin float64) int {
+	n := float64(a.simulations)
+	attacker := a.totalAttackerWins / n
+	defender := a.totalDefenderWins / n
+	variance := math.Max(attacker*(1-attacker), defender*(1-defender))
+
+	m := margin / 100
+	return int(math.Ceil(z95 * z95 * variance / (m * m)))
+}
+
+// mergeHistogram adds the counts of the histogram b into a
+func mergeHistogram(a, b map[int]int) {
+	for value, count := range b {
+		a[value] += count
+	}
+}
+
+// countFormations returns the total number of formations counted within a map
+// of formation strings to their frequency
+func countFormations(formations map[string]int) (n int) {
+	for _, count := range formations {
+		n += count
+	}
+	return n
+}
+
 func formationSliceToString(fs []map[string]int) string {
 	var ss []string
 	for _, f := range fs {
@@ -0,0 +1,95 @@
+package oddsengine
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSummaryAccumulatorMerge(t *testing.T) {
+	profiles := []ConflictProfile{
+		{
+			Rounds:                 2,
+			AttackerHits:           []int{2, 1},
+			DefenderHits:           []int{1, 0},
+			AttackerIpcLoss:        3,
+			DefenderIpcLoss:        9,
+			AttackerUnitsRemaining: formationToSortedSlice(map[string]int{"inf": 1, "tan": 1}),
+			TerritoryCaptured:      true,
+			Outcome:                1,
+		},
+		{
+			Rounds:                 1,
+			AttackerHits:           []int{0},
+			DefenderHits:           []int{2},
+			AttackerIpcLoss:        9,
+			DefenderUnitsRemaining: formationToSortedSlice(map[string]int{"inf": 3}),
+			AAAHits:                1,
+			Outcome:                -1,
+		},
+		{
+			Rounds:                 2,
+			AttackerHits:           []int{2, 1},
+			DefenderHits:           []int{1, 1},
+			AttackerIpcLoss:        6,
+			DefenderIpcLoss:        9,
+			AttackerUnitsRemaining: formationToSortedSlice(map[string]int{"tan": 1}),
+			TerritoryCaptured:      true,
+			Outcome:                1,
+		},
+		// A conflict that is over before the first round is fought
+		{
+			Rounds:                 0,
+			AttackerUnitsRemaining: formationToSortedSlice(map[string]int{"fig": 4}),
+			DefenderUnitsRemaining: formationToSortedSlice(map[string]int{"sub": 10}),
+			Outcome:                0,
+		},
+	}
+
+	expected := generateSummary(profiles)
+
+	// Fold the profiles into two partial accumulators, in reverse, and merge
+	// them back together.
+	first, second := newSummaryAccumulator(), newSummaryAccumulator()
+	second.add(&profiles[3])
+	second.add(&profiles[2])
+	first.add(&profiles[1])
+	first.add(&profiles[0])
+	first.merge(second)
+
+	if actual := first.summary(); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("merged accumulators did not match\nexpected: %+v\nactual: %+v", expected, actual)
+	}
+
+	if expected.TotalSimulations != 4 || expected.AttackerWinPercentage != 50 || expected.TerritoryCapturedPercentage != 50 || expected.AverageRounds != 1.25 {
+		t.Errorf("summary was not generated correctly\n%+v", expected)
+	}
+	if expected.AvgIpcSwing != 0 {
+		t.Errorf("IPC swing was not generated correctly\n%+v", expected)
+	}
+
+	valued := newSummaryAccumulator()
+	valued.territoryValue = 10
+	for i := range profiles {
+		valued.add(&profiles[i])
+	}
+	if summary := valued.summary(); summary.AvgIpcSwing != 5 {
+		t.Errorf("territory value was not counted in the IPC swing\n%+v", summary)
+	}
+	loss := expected.Distributions.AttackerIpcLoss
+	if !reflect.DeepEqual(loss.Histogram, map[int]int{0: 1, 3: 1, 6: 1, 9: 1}) || loss.P10 != 0 || loss.P50 != 3 || loss.P90 != 9 {
+		t.Errorf("distributions were not generated correctly\n%+v", expected.Distributions)
+	}
+	if !reflect.DeepEqual(expected.Distributions.Rounds.Histogram, map[int]int{0: 1, 1: 1, 2: 2}) {
+		t.Errorf("distributions were not generated correctly\n%+v", expected.Distributions)
+	}
+	if len(expected.FirstRoundResults) != 2 || expected.FirstRoundResults[1].Frequency != 2 {
+		t.Errorf("first round results were not generated correctly\n%+v", expected.FirstRoundResults)
+	}
+}
+
+func TestEmptySummaryAccumulator(t *testing.T) {
+	summary := newSummaryAccumulator().summary()
+	if summary.TotalSimulations != 0 || summary.AttackerWinPercentage != 0 {
+		t.Errorf("an empty accumulator should create an empty summary\n%+v", summary)
+	}
+}
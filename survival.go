@@ -0,0 +1,84 @@
+package oddsengine
+
+// UnitSurvival is how the units of a kind fared across the conflicts of a
+// Summary, whatever the outcome of each conflict. Damaged, reserved and
+// carrier based units are counted along with the rest of their kind.
+// Submerged units, bombarding ships and the fleet of an amphibious assault
+// left at sea survive.
+type UnitSurvival struct {
+	// SurvivalPercentage The percentage of conflicts in which at least one of
+	// the units survived
+	SurvivalPercentage float64 `json:"survivalPercentage"`
+
+	// ExpectedSurvivors The number of units surviving on average
+	ExpectedSurvivors float64 `json:"expectedSurvivors"`
+
+	// Survivors The number of conflicts by the number of units surviving
+	Survivors map[int]int `json:"survivors"`
+}
+
+// addSurvivors records the units surviving a conflict, by alias, into
+// survivors. Only the numbers above 0 are recorded, the conflicts in which none
+// of a kind survived being the rest of them.
+func addSurvivors(survivors map[string]map[int]int, units ...[]map[string]int) {
+	counts := map[string]int{}
+	for _, formation := range units {
+		for _, unit := range formation {
+			for alias, n := range unit {
+				counts[realAlias(alias)] += n
+			}
+		}
+	}
+
+	for alias, n := range counts {
+		if survivors[alias] == nil {
+			survivors[alias] = map[int]int{}
+		}
+		survivors[alias][n]++
+	}
+}
+
+// mergeSurvivors adds the survivors of b into a
+func mergeSurvivors(a, b map[string]map[int]int) {
+	for alias, histogram := range b {
+		if a[alias] == nil {
+			a[alias] = map[int]int{}
+		}
+		mergeHistogram(a[alias], histogram)
+	}
+}
+
+// unitSurvival creates the UnitSurvival of every alias out of the survivors of
+// n conflicts. The aliases of the formation, which may never have survived,
+// are included too.
+func unitSurvival(survivors map[string]map[int]int, formation map[string]int, n int) map[string]UnitSurvival {
+	aliases := map[string]bool{}
+	for alias := range survivors {
+		aliases[alias] = true
+	}
+	for alias := range formation {
+		aliases[realAlias(alias)] = true
+	}
+
+	survival := make(map[string]UnitSurvival, len(aliases))
+	for alias := range aliases {
+		histogram := copyHistogram(survivors[alias])
+
+		var survived, total int
+		for count, conflicts := range histogram {
+			survived += conflicts
+			total += count * conflicts
+		}
+		if n > survived {
+			histogram[0] = n - survived
+		}
+
+		survival[alias] = UnitSurvival{
+			SurvivalPercentage: round(float64(survived)/float64(n)*100, 2),
+			ExpectedSurvivors:  round(float64(total)/float64(n), 2),
+			Survivors:          histogram,
+		}
+	}
+
+	return survival
+}
@@ -0,0 +1,83 @@
+package oddsengine
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnitSurvival(t *testing.T) {
+	survivors := map[string]map[int]int{}
+	addSurvivors(survivors, formationToSortedSlice(map[string]int{"inf": 2, "+tan": 1, "tan": 1}))
+	addSurvivors(survivors, formationToSortedSlice(map[string]int{"-bat": 1}), formationToSortedSlice(map[string]int{"sub": 1}))
+	addSurvivors(survivors, nil)
+
+	merged := map[string]map[int]int{}
+	mergeSurvivors(merged, survivors)
+	mergeSurvivors(merged, map[string]map[int]int{"inf": {1: 1}})
+
+	expected := map[string]UnitSurvival{
+		"inf": {SurvivalPercentage: 50, ExpectedSurvivors: 0.75, Survivors: map[int]int{0: 2, 1: 1, 2: 1}},
+		"tan": {SurvivalPercentage: 25, ExpectedSurvivors: 0.5, Survivors: map[int]int{0: 3, 2: 1}},
+		"bat": {SurvivalPercentage: 25, ExpectedSurvivors: 0.25, Survivors: map[int]int{0: 3, 1: 1}},
+		"sub": {SurvivalPercentage: 25, ExpectedSurvivors: 0.25, Survivors: map[int]int{0: 3, 1: 1}},
+		"fig": {SurvivalPercentage: 0, ExpectedSurvivors: 0, Survivors: map[int]int{0: 4}},
+	}
+
+	actual := unitSurvival(merged, map[string]int{"*fig": 2, "inf": 3}, 4)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unit survival was not calculated correctly\nexpected: %+v\nactual: %+v", expected, actual)
+	}
+}
+
+func TestUnitSurvivalSummary(t *testing.T) {
+	attackers := map[string]int{"inf": 3, "tan": 1, "fig": 1}
+	defenders := map[string]int{"inf": 3, "art": 1}
+
+	summary, err := NewSimulator(WithIterations(2000), WithSeed(1), WithRetreat(RetreatPolicy{AfterRounds: 1})).GetSummary(attackers, defenders)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for side, survival := range map[string]map[string]UnitSurvival{"attacker": summary.AttackerUnitSurvival, "defender": summary.DefenderUnitSurvival} {
+		formation := attackers
+		if side == "defender" {
+			formation = defenders
+		}
+		if len(survival) != len(formation) {
+			t.Errorf("%s unit survival is missing units\n%+v", side, survival)
+		}
+
+		for alias, s := range survival {
+			if sumHistogram(s.Survivors) != summary.TotalSimulations {
+				t.Errorf("%s %s survivors do not cover every conflict\n%+v", side, alias, s)
+			}
+			if s.ExpectedSurvivors > float64(formation[alias]) || (s.SurvivalPercentage == 0) != (s.ExpectedSurvivors == 0) {
+				t.Errorf("%s %s survival is inconsistent\n%+v", side, alias, s)
+			}
+		}
+	}
+
+	// Attacking units survive the conflicts they retreat from as well as
+	// those they win
+	if summary.AttackerRetreatPercentage == 0 || summary.AttackerUnitSurvival["tan"].SurvivalPercentage <= summary.AttackerWinPercentage {
+		t.Errorf("attacking survivors were only counted for wins\n%+v", summary)
+	}
+
+	// Bombarding ships can not be hit, they survive every conflict
+	summary, err = NewSimulator(WithIterations(500), WithSeed(1)).GetSummary(map[string]int{"inf": 1, "bat": 1}, map[string]int{"inf": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := UnitSurvival{SurvivalPercentage: 100, ExpectedSurvivors: 1, Survivors: map[int]int{1: 500}}
+	if actual := summary.AttackerUnitSurvival["bat"]; !reflect.DeepEqual(expected, actual) {
+		t.Errorf("bombarding ships did not survive\nexpected: %+v\nactual: %+v", expected, actual)
+	}
+}
+
+// sumHistogram returns the number of conflicts counted in a histogram
+func sumHistogram(histogram map[int]int) (n int) {
+	for _, count := range histogram {
+		n += count
+	}
+	return n
+}
@@ -0,0 +1,313 @@
+package oddsengine
+
+import "sort"
+
+// The phases of a round recorded in a Trace
+const (
+	// PhaseKamikaze the defending kamikaze strike on the attacking surface
+	// ships, before the first round
+	PhaseKamikaze = "kamikaze"
+
+	// PhaseAAA the defending AAA fire at the attacking aircraft, before the
+	// first round
+	PhaseAAA = "aaa"
+
+	// PhaseBombard the offshore bombardment of the attacking ships, before the
+	// first round
+	PhaseBombard = "bombard"
+
+	// PhaseSurpriseAttack the submarine surprise attack
+	PhaseSurpriseAttack = "surpriseAttack"
+
+	// PhaseAircraft the aircraft fire, rolled apart as it may be unable to
+	// hit submarines
+	PhaseAircraft = "aircraft"
+
+	// PhaseSubs the submarine fire, rolled apart as it may only hit ships
+	PhaseSubs = "subs"
+
+	// PhaseMain the fire of every other unit
+	PhaseMain = "main"
+
+	// PhaseCasualties the casualties taken by a side
+	PhaseCasualties = "casualties"
+)
+
+// Trace is the record of a single conflict, every roll and casualty of it in
+// the order they happened. An amphibious assault fighting a sea battle first
+// is traced as two battles.
+type Trace struct {
+	// Battles The battles fought, in order
+	Battles []TraceBattle `json:"battles"`
+
+	// Outcome The outcome of the conflict, as in a ConflictProfile
+	Outcome int `json:"outcome"`
+
+	// AttackerIpcLoss The number of IPC's that the attacker lost
+	AttackerIpcLoss int `json:"attackerIpcLoss"`
+
+	// DefenderIpcLoss The number of IPC's that the defender lost
+	DefenderIpcLoss int `json:"defenderIpcLoss"`
+
+	// conflict is the index of the conflict within its simulation
+	conflict int
+}
+
+// TraceBattle is the record of a battle of a Trace
+type TraceBattle struct {
+	// Attackers The attacking units the battle started with
+	Attackers map[string]int `json:"attackers"`
+
+	// Defenders The defending units the battle started with
+	Defenders map[string]int `json:"defenders"`
+
+	// Rounds The rounds of the battle, the special attacks made before the
+	// first round being part of it
+	Rounds []TraceRound `json:"rounds"`
+
+	// Outcome The outcome of the battle, as in a ConflictProfile
+	Outcome int `json:"outcome"`
+}
+
+// TraceRound is the record of a round of a battle
+type TraceRound struct {
+	// Round The round, starting at 1
+	Round int `json:"round"`
+
+	// Phases The phases of the round, in order
+	Phases []TracePhase `json:"phases"`
+}
+
+// TracePhase is a side rolling, or taking its casualties
+type TracePhase struct {
+	// Phase One of the Phase constants
+	Phase string `json:"phase"`
+
+	// Side Either "attacker" or "defender", the side rolling or taking its
+	// casualties
+	Side string `json:"side"`
+
+	// Rolls The dice rolled in the phase, by hit value
+	Rolls []TraceRoll `json:"rolls,omitempty"`
+
+	// Hits The hits scored in the phase, or the hits to take casualties for
+	Hits int `json:"hits"`
+
+	// Lost The units lost as casualties, by alias
+	Lost map[string]int `json:"lost,omitempty"`
+
+	// Damaged The capital ships damaged by the hits, by alias
+	Damaged map[string]int `json:"damaged,omitempty"`
+}
+
+// TraceRoll is a group of dice rolled at the same hit value. The faces are
+// only known when the simulator rolls its own dice, Dice set by WithDice only
+// reporting their hits. Under Low Luck the hits scored without rolling are
+// recorded with no dice, the hit value being the power they were scored from.
+// The dice of a multi roll unit are all recorded, though the unit scores a
+// single hit at most.
+type TraceRoll struct {
+	// HitValue The value a die had to roll or less to score a hit
+	HitValue int `json:"hitValue"`
+
+	// Dice The number of dice rolled
+	Dice int `json:"dice"`
+
+	// Faces The face of each die rolled
+	Faces []int `json:"faces,omitempty"`
+
+	// Hits The number of hits scored
+	Hits int `json:"hits"`
+}
+
+// traceSide names the side of a Trace
+func traceSide(attacking bool) string {
+	if attacking {
+		return "attacker"
+	}
+	return "defender"
+}
+
+// The methods below record onto the trace, doing nothing when it is nil so
+// that the engine may call them whether or not it is tracing.
+
+// battle starts recording a battle between the attackers and defenders
+func (t *Trace) battle(attackers, defenders map[string]int) {
+	if t == nil {
+		return
+	}
+
+	t.Battles = append(t.Battles, TraceBattle{
+		Attackers: copyFormation(attackers),
+		Defenders: copyFormation(defenders),
+		Rounds:    []TraceRound{},
+	})
+}
+
+// endBattle records the outcome of the current battle
+func (t *Trace) endBattle(outcome int) {
+	if t == nil || len(t.Battles) == 0 {
+		return
+	}
+
+	t.Battles[len(t.Battles)-1].Outcome = outcome
+}
+
+// round starts recording a round of the current battle
+func (t *Trace) round() {
+	if t == nil || len(t.Battles) == 0 {
+		return
+	}
+
+	b := &t.Battles[len(t.Battles)-1]
+	b.Rounds = append(b.Rounds, TraceRound{Round: len(b.Rounds) + 1})
+}
+
+// phase starts recording a phase of the current round
+func (t *Trace) phase(phase string, attacking bool) {
+	r := t.currentRound()
+	if r == nil {
+		return
+	}
+
+	r.Phases = append(r.Phases, TracePhase{Phase: phase, Side: traceSide(attacking)})
+}
+
+// hits records the hits scored in the current phase. A phase in which no dice
+// were rolled and no hits scored is dropped, the side having had no units to
+// fire with.
+func (t *Trace) hits(hits int) {
+	p := t.currentPhase()
+	if p == nil {
+		return
+	}
+
+	if len(p.Rolls) == 0 && hits == 0 {
+		r := t.currentRound()
+		r.Phases = r.Phases[:len(r.Phases)-1]
+		return
+	}
+	p.Hits = hits
+}
+
+// roll records a group of dice rolled in the current phase
+func (t *Trace) roll(dice, hitValue int, faces []int, hits int) {
+	if p := t.currentPhase(); p != nil {
+		p.Rolls = append(p.Rolls, TraceRoll{HitValue: hitValue, Dice: dice, Faces: faces, Hits: hits})
+	}
+}
+
+// casualties records the casualties a side took for the hits
+func (t *Trace) casualties(attacking bool, hits int, before, after map[string]int) {
+	r := t.currentRound()
+	if r == nil {
+		return
+	}
+
+	r.Phases = append(r.Phases, casualtyPhase(attacking, hits, before, after))
+}
+
+// casualtyPhase creates the phase of a side taking casualties for the hits, the
+// formation before being compared to the formation after
+func casualtyPhase(attacking bool, hits int, before, after map[string]int) TracePhase {
+	p := TracePhase{Phase: PhaseCasualties, Side: traceSide(attacking), Hits: hits}
+
+	lost, damaged, remaining := map[string]int{}, map[string]int{}, map[string]int{}
+	for key, n := range after {
+		remaining[realAlias(key)] += n
+		if key[0] == '-' && n > before[key] {
+			damaged[realAlias(key)] += n - before[key]
+		}
+	}
+	for key, n := range before {
+		remaining[realAlias(key)] -= n
+	}
+	for alias, n := range remaining {
+		if n < 0 {
+			lost[alias] = -n
+		}
+	}
+
+	if len(lost) > 0 {
+		p.Lost = lost
+	}
+	if len(damaged) > 0 {
+		p.Damaged = damaged
+	}
+
+	return p
+}
+
+// currentRound returns the round being recorded, nil if there is none
+func (t *Trace) currentRound() *TraceRound {
+	if t == nil || len(t.Battles) == 0 {
+		return nil
+	}
+
+	b := &t.Battles[len(t.Battles)-1]
+	if len(b.Rounds) == 0 {
+		return nil
+	}
+
+	return &b.Rounds[len(b.Rounds)-1]
+}
+
+// currentPhase returns the phase being recorded, nil if there is none
+func (t *Trace) currentPhase() *TracePhase {
+	r := t.currentRound()
+	if r == nil || len(r.Phases) == 0 {
+		return nil
+	}
+
+	return &r.Phases[len(r.Phases)-1]
+}
+
+// TraceConflict resolves a single conflict, tracing it. Runs against the
+// default Simulator.
+func TraceConflict(attackers, defenders map[string]int) (*Trace, error) {
+	return defaultSimulator.TraceConflict(attackers, defenders)
+}
+
+// TraceConflict resolves a single conflict between the attackers and
+// defenders, recording every roll and casualty of it. A seeded simulator
+// traces the first conflict of its simulations.
+func (s *Simulator) TraceConflict(attackers, defenders map[string]int) (*Trace, error) {
+	var err error
+
+	err = s.checkUnitValidity(attackers)
+	if err != nil {
+		return &Trace{}, err
+	}
+
+	err = s.checkUnitValidity(defenders)
+	if err != nil {
+		return &Trace{}, err
+	}
+
+	w := s.worker(streamSeed(s.simulationSeed(), 0))
+	_, trace := w.traceConflict(attackers, defenders, s.customizeOol(attackers, defenders))
+
+	return trace, nil
+}
+
+// traceConflict resolves the conflict just like resolveConflict, returning its
+// trace along with its profile
+func (s *Simulator) traceConflict(attackers, defenders map[string]int, ool *conflictOol) (*ConflictProfile, *Trace) {
+	w := *s
+	w.trace = &Trace{Battles: []TraceBattle{}}
+	profile := w.resolveConflict(attackers, defenders, ool)
+
+	w.trace.Outcome = profile.Outcome
+	w.trace.AttackerIpcLoss = profile.AttackerIpcLoss
+	w.trace.DefenderIpcLoss = profile.DefenderIpcLoss
+
+	return profile, w.trace
+}
+
+// sortTraces sorts traces by the order of their conflicts within the
+// simulation
+func sortTraces(traces []*Trace) {
+	sort.Slice(traces, func(i, j int) bool {
+		return traces[i].conflict < traces[j].conflict
+	})
+}
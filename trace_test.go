@@ -0,0 +1,160 @@
+package oddsengine
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestTraceConflict(t *testing.T) {
+	s := NewSimulator(WithSeed(3))
+	attackers := map[string]int{"inf": 3, "art": 1, "fig": 2}
+	defenders := map[string]int{"inf": 4, "aaa": 1}
+
+	trace, err := s.TraceConflict(attackers, defenders)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trace.Battles) != 1 || len(trace.Battles[0].Rounds) == 0 {
+		t.Fatalf("the battle was not traced\n%+v", trace)
+	}
+	if trace.Battles[0].Outcome != trace.Outcome {
+		t.Errorf("battle outcome %v does not match the conflict outcome %v", trace.Battles[0].Outcome, trace.Outcome)
+	}
+
+	// AAA fires before the first round
+	if first := trace.Battles[0].Rounds[0].Phases[0]; first.Phase != PhaseAAA || first.Side != "defender" {
+		t.Errorf("AAA fire was not traced first\n%+v", first)
+	}
+
+	var lost int
+	for _, r := range trace.Battles[0].Rounds {
+		for _, p := range r.Phases {
+			if p.Phase == PhaseCasualties {
+				for alias, n := range p.Lost {
+					lost += s.units.Find(alias).Cost * n
+				}
+				continue
+			}
+
+			var hits int
+			for _, roll := range p.Rolls {
+				hits += roll.Hits
+				if len(roll.Faces) != roll.Dice {
+					t.Errorf("round %v %v: %v faces for %v dice", r.Round, p.Phase, len(roll.Faces), roll.Dice)
+				}
+
+				var faceHits int
+				for _, face := range roll.Faces {
+					if face <= roll.HitValue {
+						faceHits++
+					}
+				}
+				if faceHits != roll.Hits {
+					t.Errorf("round %v %v: faces %v at %v do not score %v hits", r.Round, p.Phase, roll.Faces, roll.HitValue, roll.Hits)
+				}
+			}
+			if hits != p.Hits {
+				t.Errorf("round %v %v: rolls score %v hits, phase has %v", r.Round, p.Phase, hits, p.Hits)
+			}
+		}
+	}
+	if lost != trace.AttackerIpcLoss+trace.DefenderIpcLoss {
+		t.Errorf("casualties are worth %v IPC's, the conflict lost %v", lost, trace.AttackerIpcLoss+trace.DefenderIpcLoss)
+	}
+
+	b, err := json.Marshal(trace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Trace
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&decoded, trace) {
+		t.Errorf("trace did not survive a JSON round trip\nexpected: %+v\nactual: %+v", trace, decoded)
+	}
+}
+
+func TestTraceCasualties(t *testing.T) {
+	var trace *Trace
+	// A nil trace records nothing
+	trace.casualties(true, 1, map[string]int{"inf": 1}, map[string]int{})
+
+	trace = &Trace{}
+	trace.battle(map[string]int{"inf": 2, "bat": 1}, map[string]int{"inf": 1})
+	trace.round()
+	trace.casualties(true, 2, map[string]int{"inf": 2, "bat": 1}, map[string]int{"inf": 1, "-bat": 1})
+
+	expected := TracePhase{
+		Phase:   PhaseCasualties,
+		Side:    "attacker",
+		Hits:    2,
+		Lost:    map[string]int{"inf": 1},
+		Damaged: map[string]int{"bat": 1},
+	}
+	if actual := *trace.currentPhase(); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("casualties were not traced correctly\nexpected: %+v\nactual: %+v", expected, actual)
+	}
+}
+
+func TestTraceDice(t *testing.T) {
+	// The faces of dice set by WithDice are not known
+	s := NewSimulator(WithDice(&scriptedDice{faces: []int{1, 6}}))
+	trace, err := s.TraceConflict(map[string]int{"inf": 2}, map[string]int{"inf": 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roll := trace.Battles[0].Rounds[0].Phases[0].Rolls[0]
+	if expected := (TraceRoll{HitValue: 1, Dice: 2, Hits: 1}); !reflect.DeepEqual(expected, roll) {
+		t.Errorf("scripted roll was not traced correctly\nexpected: %+v\nactual: %+v", expected, roll)
+	}
+}
+
+func TestSampleTraces(t *testing.T) {
+	attackers := map[string]int{"inf": 3, "tan": 1}
+	defenders := map[string]int{"inf": 3}
+
+	plain, err := NewSimulator(WithIterations(500), WithSeed(7)).GetSummary(attackers, defenders)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewSimulator(WithIterations(500), WithSeed(7), WithSampleTraces(3))
+	summary, err := s.GetSummary(attackers, defenders)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summary.Traces) != 3 {
+		t.Fatalf("expected 3 traces, got %v", len(summary.Traces))
+	}
+
+	// Tracing leaves the rolls, and so the summary, unchanged
+	traces := summary.Traces
+	summary.Traces = nil
+	if !reflect.DeepEqual(plain, summary) {
+		t.Errorf("tracing changed the summary\nexpected: %+v\nactual: %+v", plain, summary)
+	}
+
+	trace, err := s.TraceConflict(attackers, defenders)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(traces[0], trace) {
+		t.Errorf("the first sample trace is not the seeded trace\nexpected: %+v\nactual: %+v", trace, traces[0])
+	}
+
+	// The sea battle of an amphibious assault is traced first
+	summary, err = NewSimulator(WithIterations(10), WithSampleTraces(1)).GetAmphibiousSummary(map[string]int{}, map[string]int{"inf": 1}, AmphibiousAssault{
+		Seaborne:     map[string]int{"inf": 2},
+		Fleet:        map[string]int{"tra": 1, "bat": 2},
+		SeaDefenders: map[string]int{"sub": 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if battles := summary.Traces[0].Battles; len(battles) != 2 || battles[0].Defenders["sub"] != 1 {
+		t.Errorf("the assault was not traced as two battles\n%+v", battles)
+	}
+}
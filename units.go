@@ -13,6 +13,8 @@ type Unit struct {
 	IsSub            bool
 	IsAircraft       bool
 	IsBunker         bool
+	IsTransport      bool
+	CarrierCapacity  int
 	CapitalShip      bool
 	CanBombard       bool
 	CanTakeTerritory bool
@@ -49,6 +51,12 @@ type ByAttackingPower struct{ Units }
 // ByCost sorts the units by the lowest Cost value of the unit
 type ByCost struct{ Units }
 
+// ByAttackingValue sorts the units by the lowest Attack value per IPC of Cost
+type ByAttackingValue struct{ Units }
+
+// ByDefendingValue sorts the units by the lowest Defend value per IPC of Cost
+type ByDefendingValue struct{ Units }
+
 // Less implementing Sortable
 func (p ByDefendingPower) Less(i, j int) bool {
 	if p.Units[i].Alias == "aaa" || p.Units[i].Alias == "raaa" || p.Units[i].Alias == "aag" {
@@ -88,6 +96,31 @@ func (p ByCost) Less(i, j int) bool {
 	return p.Units[i].Cost < p.Units[j].Cost
 }
 
+// Less implementing Sortable
+func (p ByAttackingValue) Less(i, j int) bool {
+	return lessValuePerIpc(p.Units[i], p.Units[j], p.Units[i].Attack, p.Units[j].Attack)
+}
+
+// Less implementing Sortable
+func (p ByDefendingValue) Less(i, j int) bool {
+	return lessValuePerIpc(p.Units[i], p.Units[j], p.Units[i].Defend, p.Units[j].Defend)
+}
+
+// lessValuePerIpc compares the hit value per IPC of two units, the cheaper unit
+// being less when both are worth as much. Units costing nothing are worth the
+// most.
+func lessValuePerIpc(a, b Unit, aValue, bValue int) bool {
+	if a.Alias == "aaa" || a.Alias == "raaa" || a.Alias == "aag" {
+		return false
+	} else if b.Alias == "aaa" || b.Alias == "raaa" || b.Alias == "aag" {
+		return true
+	} else if aValue*b.Cost == bValue*a.Cost {
+		return a.Cost < b.Cost
+	}
+
+	return aValue*b.Cost < bValue*a.Cost
+}
+
 // getUnitsForGame returns a Units type containing all the units that are
 // valid for a particular game identified by the game string passed in
 func getUnitsForGame(game string) (p Units) {
@@ -240,14 +273,15 @@ func get1940Units() Units {
 			},
 		},
 		Unit{
-			Alias:       "car",
-			Name:        "Aircraft Carrier",
-			Cost:        16,
-			Attack:      0,
-			Defend:      2,
-			IsShip:      true,
-			CanBombard:  false,
-			CapitalShip: true,
+			Alias:           "car",
+			Name:            "Aircraft Carrier",
+			Cost:            16,
+			Attack:          0,
+			Defend:          2,
+			IsShip:          true,
+			CanBombard:      false,
+			CapitalShip:     true,
+			CarrierCapacity: 2,
 		},
 		Unit{
 			Alias:      "hbom",
@@ -348,7 +382,19 @@ func get1942Units() Units {
 
 	// The 1942 Battleship is more expensive
 	p = p.Delete("car")
+
+	// Transports are defenseless from 1942 on
+	p = p.Delete("tra")
 	p = append(p,
+		Unit{
+			Alias:       "tra",
+			Name:        "Transport",
+			Cost:        7,
+			Attack:      0,
+			Defend:      0,
+			IsShip:      true,
+			IsTransport: true,
+		},
 		Unit{
 			Alias:  "aaa",
 			Name:   "Anti-Aircraft Artillery",
@@ -385,14 +431,15 @@ func get1942Units() Units {
 			CapitalShip: true,
 		},
 		Unit{
-			Alias:       "car",
-			Name:        "Aircraft Carrier",
-			Cost:        14,
-			Attack:      1,
-			Defend:      2,
-			IsShip:      true,
-			CanBombard:  false,
-			CapitalShip: false,
+			Alias:           "car",
+			Name:            "Aircraft Carrier",
+			Cost:            14,
+			Attack:          1,
+			Defend:          2,
+			IsShip:          true,
+			CanBombard:      false,
+			CapitalShip:     false,
+			CarrierCapacity: 2,
 		},
 	)
 
@@ -479,15 +526,27 @@ func get1941Units() Units {
 			Defend: 2,
 			IsShip: true,
 		},
+		// The 1941 transport defends itself and may be taken as a casualty
+		// like any other ship
 		Unit{
-			Alias:       "car",
-			Name:        "Aircraft Carrier",
-			Cost:        12,
-			Attack:      1,
-			Defend:      2,
+			Alias:       "tra",
+			Name:        "Transport",
+			Cost:        8,
+			Attack:      0,
+			Defend:      1,
 			IsShip:      true,
-			CanBombard:  false,
-			CapitalShip: false,
+			IsTransport: true,
+		},
+		Unit{
+			Alias:           "car",
+			Name:            "Aircraft Carrier",
+			Cost:            12,
+			Attack:          1,
+			Defend:          2,
+			IsShip:          true,
+			CanBombard:      false,
+			CapitalShip:     false,
+			CarrierCapacity: 2,
 		},
 		Unit{
 			Alias:       "bat",
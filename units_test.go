@@ -17,9 +17,9 @@ func TestUnitSwitching(t *testing.T) {
 		{"1940", []string{"kam", "inf", "imec", "mec", "art", "aart", "raaa", "sub", "ssub", "tan", "des", "fig", "jfig", "tac", "cru", "hbom", "bom", "car", "bat", "aaa"}},
 	}
 	for _, tt := range values {
-		SetGame(tt.game)
-		sort.Sort(ByCost{activeUnits})
-		actual := unitsToSlice(activeUnits)
+		s := NewSimulator(WithGame(tt.game))
+		sort.Sort(ByCost{s.units})
+		actual := unitsToSlice(s.units)
 		if !reflect.DeepEqual(tt.units, actual) {
 			t.Errorf("Units for game %v not generated correctly\nexpected: %v\nactual: %v", tt.game, tt.units, actual)
 		}
@@ -78,6 +78,8 @@ func TestUnitsSorting(t *testing.T) {
 	byValueOrder := []string{"inf", "art", "cru", "car", "bat"}
 	byAttackingOrder := []string{"inf", "car", "art", "cru", "bat"}
 	byDefendingOrder := []string{"inf", "art", "car", "cru", "bat"}
+	byAttackingValueOrder := []string{"car", "bat", "cru", "inf", "art"}
+	byDefendingValueOrder := []string{"car", "bat", "cru", "art", "inf"}
 
 	sort.Sort(ByCost{units})
 	actual = unitsToSlice(units)
@@ -100,6 +102,20 @@ func TestUnitsSorting(t *testing.T) {
 		t.Errorf("sorting units by defence is not working\nexpected: %v\nactual: %v", byDefendingOrder, actual)
 	}
 
+	sort.Sort(ByAttackingValue{units})
+	actual = unitsToSlice(units)
+
+	if !reflect.DeepEqual(actual, byAttackingValueOrder) {
+		t.Errorf("sorting units by attack per IPC is not working\nexpected: %v\nactual: %v", byAttackingValueOrder, actual)
+	}
+
+	sort.Sort(ByDefendingValue{units})
+	actual = unitsToSlice(units)
+
+	if !reflect.DeepEqual(actual, byDefendingValueOrder) {
+		t.Errorf("sorting units by defence per IPC is not working\nexpected: %v\nactual: %v", byDefendingValueOrder, actual)
+	}
+
 }
 
 func unitsToSlice(p Units) []string {